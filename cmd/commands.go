@@ -0,0 +1,102 @@
+package main
+
+import (
+	"elastic-btree/internal/tree"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// commandResult is the outcome of running a single tree command. It is shared
+// by the interactive shell and script execution.
+type commandResult struct {
+	Line    int         `json:"line,omitempty"`  // Script line number (exec only)
+	Command string      `json:"command"`         // Command name
+	Key     *int        `json:"key,omitempty"`   // Key the command operated on
+	Value   interface{} `json:"value,omitempty"` // Inserted or found value
+	Found   *bool       `json:"found,omitempty"` // Whether the key existed (search/delete)
+	Error   string      `json:"error,omitempty"` // Error message if the command failed
+}
+
+// Text returns the human-readable form of the result.
+func (r commandResult) Text() string {
+	switch {
+	case r.Error != "":
+		return "error: " + r.Error
+	case r.Found != nil && !*r.Found:
+		return fmt.Sprintf("Key %d not found", *r.Key)
+	case r.Command == "insert":
+		return fmt.Sprintf("Inserted key %d", *r.Key)
+	case r.Command == "delete":
+		return fmt.Sprintf("Deleted key %d", *r.Key)
+	case r.Command == "search":
+		return fmt.Sprintf("Found key %d: %v", *r.Key, r.Value)
+	default:
+		return r.Command
+	}
+}
+
+// isTreeCommand reports whether runTreeCommand handles the named command.
+func isTreeCommand(name string) bool {
+	return name == "insert" || name == "delete" || name == "search"
+}
+
+// runTreeCommand applies an insert, delete or search command to the tree. It
+// reports whether the tree was modified.
+func runTreeCommand(t *tree.Tree, args []string) (commandResult, bool, error) {
+	result := commandResult{Command: args[0]}
+
+	switch args[0] {
+	case "insert":
+		if len(args) < 3 {
+			return result, false, errors.New("usage: insert <key> <value>")
+		}
+		key, err := parseKey(args[1])
+		if err != nil {
+			return result, false, err
+		}
+		t.Insert(key, args[2])
+		result.Key = &key
+		result.Value = args[2]
+		return result, true, nil
+	case "delete":
+		if len(args) < 2 {
+			return result, false, errors.New("usage: delete <key>")
+		}
+		key, err := parseKey(args[1])
+		if err != nil {
+			return result, false, err
+		}
+		_, found := t.Search(key)
+		if found {
+			t.Delete(key)
+		}
+		result.Key = &key
+		result.Found = &found
+		return result, found, nil
+	case "search":
+		if len(args) < 2 {
+			return result, false, errors.New("usage: search <key>")
+		}
+		key, err := parseKey(args[1])
+		if err != nil {
+			return result, false, err
+		}
+		value, found := t.Search(key)
+		result.Key = &key
+		result.Value = value
+		result.Found = &found
+		return result, false, nil
+	default:
+		return result, false, fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+// parseKey converts a command argument to a tree key.
+func parseKey(s string) (int, error) {
+	key, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid key: %v", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/config"
+	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// handleConfig prints the effective configuration.
+//
+// Usage: config show [--format table|json]
+func handleConfig(cfg *config.Config, log *logger.Logger) {
+	if len(os.Args) < 3 || os.Args[2] != "show" {
+		log.Errorf("Usage: config show [--format table|json]")
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("config show", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	fs.Parse(os.Args[3:])
+
+	var err error
+	switch *format {
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "OPTION\tVALUE\tSOURCE\tFLAG\tENV\tRELOAD\n")
+		for _, s := range cfg.Settings() {
+			source := string(s.Source)
+			if s.Source == config.FromFile {
+				source += " (" + cfg.File + ")"
+			}
+			reload := "no"
+			if s.Reloadable {
+				reload = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%q\t%s\t--%s\t%s\t%s\n", s.Name, s.Value, source, s.Flag, s.Env, reload)
+		}
+		err = tw.Flush()
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(map[string]interface{}{"file": cfg.File, "settings": cfg.Settings()})
+	default:
+		err = fmt.Errorf("unknown format %q (want table or json)", *format)
+	}
+	if err != nil {
+		log.Errorf("Config show failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+// applyRuntimeConfig applies the options that may change while the tree is
+// in use: at startup and on every config reload. Size limits set to
+// config.KeepLimit keep those saved with the tree; 0 removes a limit. On error
+// neither the tree's settings nor the log level are changed.
+func applyRuntimeConfig(t *tree.Tree, log *logger.Logger, cfg *config.Config) error {
+	settings := t.Settings()
+	invariants, err := tree.ParseInvariantMode(cfg.Invariants)
+	if err != nil {
+		return err
+	}
+	settings.Invariants = invariants
+	if cfg.MaxEntries != config.KeepLimit {
+		settings.MaxEntries = cfg.MaxEntries
+	}
+	if cfg.MaxBytes != config.KeepLimit {
+		settings.MaxBytes = cfg.MaxBytes
+	}
+	if cfg.Eviction != "" {
+		settings.Eviction = tree.EvictionPolicy(cfg.Eviction)
+	}
+	if err := t.Configure(settings); err != nil {
+		return err
+	}
+
+	log.SetLevel(cfg.LogLevel)
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"elastic-btree/pkg/config"
+	"elastic-btree/pkg/logger"
+	"io"
+	"testing"
+)
+
+func TestApplyRuntimeConfigRejectsInvalid(t *testing.T) {
+	tr := newImportTree()
+	tr.MaxEntries = 10
+	log := logger.New(logger.Info, io.Discard)
+
+	cfg := &config.Config{LogLevel: logger.Debug, Invariants: "most", MaxEntries: 5, MaxBytes: config.KeepLimit}
+	if err := applyRuntimeConfig(tr, log, cfg); err == nil {
+		t.Fatal("applyRuntimeConfig accepted an unknown invariant mode")
+	}
+	cfg.Invariants, cfg.Eviction = "", "fifo"
+	if err := applyRuntimeConfig(tr, log, cfg); err == nil {
+		t.Fatal("applyRuntimeConfig accepted an unknown eviction policy")
+	}
+	if log.Level() != logger.Info {
+		t.Errorf("log level changed to %v by a rejected config", log.Level())
+	}
+	if tr.MaxEntries != 10 {
+		t.Errorf("MaxEntries changed to %d by a rejected config", tr.MaxEntries)
+	}
+}
+
+func TestApplyRuntimeConfigLimits(t *testing.T) {
+	tr := newImportTree()
+	tr.MaxEntries = 10
+	tr.MaxBytes = 1000
+	log := logger.New(logger.Info, io.Discard)
+
+	tests := []struct {
+		maxEntries int
+		maxBytes   int64
+		wantCount  int
+		wantBytes  int64
+	}{
+		{config.KeepLimit, config.KeepLimit, 10, 1000},
+		{20, config.KeepLimit, 20, 1000},
+		{0, config.KeepLimit, 0, 1000},
+		{config.KeepLimit, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{LogLevel: logger.Debug, MaxEntries: tt.maxEntries, MaxBytes: tt.maxBytes}
+		if err := applyRuntimeConfig(tr, log, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got := tr.Settings(); got.MaxEntries != tt.wantCount || got.MaxBytes != tt.wantBytes {
+			t.Errorf("maxEntries %d, maxBytes %d: limits %d and %d, want %d and %d",
+				tt.maxEntries, tt.maxBytes, got.MaxEntries, got.MaxBytes, tt.wantCount, tt.wantBytes)
+		}
+	}
+	if log.Level() != logger.Debug {
+		t.Errorf("log level %v, want debug", log.Level())
+	}
+}
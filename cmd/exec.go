@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// handleExec runs a script of insert/delete/search commands against the
+// loaded tree and saves it once at the end.
+//
+// Usage: exec [--continue] [--format text|ndjson] [script|-]
+func handleExec(t *tree.Tree, s *storage.Storage, log *logger.Logger) {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	keepGoing := fs.Bool("continue", false, "continue after a failing command instead of stopping")
+	format := fs.String("format", "text", "result format: text or ndjson")
+	fs.Parse(os.Args[2:])
+
+	if *format != "text" && *format != "ndjson" {
+		log.Errorf("Invalid format: %s (must be text or ndjson)", *format)
+		os.Exit(1)
+	}
+
+	var in io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Errorf("Failed to open script: %v", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	executed, failed, saved, err := runScript(t, s, in, os.Stdout, *format, *keepGoing)
+	if err != nil {
+		log.Errorf("Exec failed: %v", err)
+	}
+	if saved {
+		log.Infof("Tree saved successfully")
+	}
+
+	log.Infof("Executed %d commands (%d failed)", executed, failed)
+	if failed > 0 || err != nil {
+		os.Exit(1)
+	}
+}
+
+// runScript runs the script with execScript and saves the tree if any command
+// changed it. Changes made before a failure are kept, as they would be with
+// one invocation per command. It reports whether the tree was saved.
+func runScript(t *tree.Tree, s *storage.Storage, in io.Reader, out io.Writer, format string, keepGoing bool) (int, int, bool, error) {
+	executed, failed, mutated, err := execScript(t, in, out, format, keepGoing)
+	if err != nil {
+		err = fmt.Errorf("failed to read script: %v", err)
+	}
+	if !mutated {
+		return executed, failed, false, err
+	}
+	if saveErr := s.SaveTree(t); saveErr != nil {
+		return executed, failed, false, fmt.Errorf("save failed: %v", saveErr)
+	}
+	return executed, failed, true, err
+}
+
+// execScript runs each non-empty, non-comment line of the script and writes
+// one result per command. It returns the number of commands executed and
+// failed and whether the tree was modified.
+func execScript(t *tree.Tree, in io.Reader, out io.Writer, format string, keepGoing bool) (int, int, bool, error) {
+	scanner := bufio.NewScanner(in)
+	encoder := json.NewEncoder(out)
+	executed, failed, mutated := 0, 0, false
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		result := commandResult{Line: lineNo}
+		args, err := splitArgs(line)
+		if err == nil {
+			result.Command = args[0]
+			if isTreeCommand(args[0]) {
+				var changed bool
+				result, changed, err = runTreeCommand(t, args)
+				result.Line = lineNo
+				mutated = mutated || changed
+			} else {
+				err = fmt.Errorf("unknown command: %s", args[0])
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+			failed++
+		}
+		executed++
+
+		if format == "ndjson" {
+			encoder.Encode(result)
+		} else {
+			fmt.Fprintf(out, "%d: %s\n", lineNo, result.Text())
+		}
+
+		if err != nil && !keepGoing {
+			break
+		}
+	}
+	return executed, failed, mutated, scanner.Err()
+}
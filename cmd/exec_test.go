@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"elastic-btree/internal/storage"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const execTestScript = `# comment lines and blank lines are skipped
+
+insert 1 one
+search 1
+bogus 2
+insert 2 "two words"
+delete 1
+`
+
+func TestExecScriptStopsOnError(t *testing.T) {
+	tr := newImportTree()
+	var out bytes.Buffer
+	executed, failed, mutated, err := execScript(tr, strings.NewReader(execTestScript), &out, "text", false)
+	if err != nil {
+		t.Fatalf("execScript failed: %v", err)
+	}
+	if executed != 3 || failed != 1 || !mutated {
+		t.Errorf("executed=%d failed=%d mutated=%v, want 3, 1, true", executed, failed, mutated)
+	}
+
+	want := "3: Inserted key 1\n4: Found key 1: one\n5: error: unknown command: bogus\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if got, want := treePairs(tr), map[int]string{1: "one"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tree contents = %v, want %v", got, want)
+	}
+}
+
+func TestExecScriptContinue(t *testing.T) {
+	tr := newImportTree()
+	var out bytes.Buffer
+	executed, failed, mutated, err := execScript(tr, strings.NewReader(execTestScript), &out, "text", true)
+	if err != nil {
+		t.Fatalf("execScript failed: %v", err)
+	}
+	if executed != 5 || failed != 1 || !mutated {
+		t.Errorf("executed=%d failed=%d mutated=%v, want 5, 1, true", executed, failed, mutated)
+	}
+
+	want := "3: Inserted key 1\n4: Found key 1: one\n5: error: unknown command: bogus\n" +
+		"6: Inserted key 2\n7: Deleted key 1\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if got, want := treePairs(tr), map[int]string{2: "two words"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tree contents = %v, want %v", got, want)
+	}
+}
+
+func TestExecScriptNDJSON(t *testing.T) {
+	tr := newImportTree()
+	var out bytes.Buffer
+	script := "insert 1 one\nsearch 1\nsearch 9\ndelete x\n"
+	if _, _, _, err := execScript(tr, strings.NewReader(script), &out, "ndjson", true); err != nil {
+		t.Fatalf("execScript failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d result lines, want 4: %q", len(lines), out.String())
+	}
+	var results []map[string]interface{}
+	for _, line := range lines {
+		var result map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		results = append(results, result)
+	}
+
+	want := []map[string]interface{}{
+		{"line": 1.0, "command": "insert", "key": 1.0, "value": "one"},
+		{"line": 2.0, "command": "search", "key": 1.0, "value": "one", "found": true},
+		{"line": 3.0, "command": "search", "key": 9.0, "found": false},
+		{"line": 4.0, "command": "delete", "error": `invalid key: strconv.Atoi: parsing "x": invalid syntax`},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+}
+
+func TestRunScriptSavesOnlyChanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		keepGoing bool
+		wantSaved bool
+		wantPairs map[int]string // Saved contents; nil means no file is written
+	}{
+		{name: "read only", script: "search 1\nsearch 2\n", wantSaved: false},
+		{name: "delete of missing key", script: "delete 1\n", wantSaved: false},
+		{name: "failure before change", script: "bogus\ninsert 1 one\n", wantSaved: false},
+		{name: "change", script: "insert 1 one\nsearch 1\n", wantSaved: true, wantPairs: map[int]string{1: "one"}},
+		{name: "change before failure", script: "insert 1 one\nbogus\ninsert 2 two\n", wantSaved: true, wantPairs: map[int]string{1: "one"}},
+		{name: "change after failure with continue", script: "bogus\ninsert 2 two\n", keepGoing: true, wantSaved: true, wantPairs: map[int]string{2: "two"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tree.json")
+			s := storage.NewStorage(path)
+			var out bytes.Buffer
+			_, _, saved, err := runScript(newImportTree(), s, strings.NewReader(tt.script), &out, "text", tt.keepGoing)
+			if err != nil {
+				t.Fatalf("runScript failed: %v", err)
+			}
+			if saved != tt.wantSaved {
+				t.Errorf("saved = %v, want %v (output %q)", saved, tt.wantSaved, out.String())
+			}
+
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				if tt.wantPairs != nil {
+					t.Fatal("tree file was not written")
+				}
+				return
+			} else if tt.wantPairs == nil {
+				t.Fatal("tree file was written without any change")
+			}
+			loaded, err := s.LoadTree()
+			if err != nil {
+				t.Fatalf("LoadTree failed: %v", err)
+			}
+			if got := treePairs(loaded); !reflect.DeepEqual(got, tt.wantPairs) {
+				t.Errorf("saved contents = %v, want %v", got, tt.wantPairs)
+			}
+		})
+	}
+}
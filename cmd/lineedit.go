@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// lineReader reads input lines for the interactive shell. When the input is a
+// terminal it offers line editing, history navigation and tab completion;
+// otherwise it falls back to plain line-buffered reads.
+type lineReader struct {
+	in       *bufio.Reader
+	out      io.Writer
+	fd       int
+	raw      bool
+	history  []string
+	complete func(line string) []string
+}
+
+// newLineReader creates a lineReader for the given input file. The complete
+// function receives the text before the cursor and returns candidate lines.
+func newLineReader(in *os.File, out io.Writer, complete func(line string) []string) *lineReader {
+	r := &lineReader{
+		in:       bufio.NewReader(in),
+		out:      out,
+		fd:       int(in.Fd()),
+		complete: complete,
+	}
+	if restore, err := makeRaw(r.fd); err == nil {
+		restore()
+		r.raw = true
+	}
+	return r
+}
+
+// ReadLine prints the prompt and returns the next line without its trailing
+// newline. It returns io.EOF when the input is exhausted or Ctrl-D is pressed
+// on an empty line.
+func (r *lineReader) ReadLine(prompt string) (string, error) {
+	if !r.raw {
+		fmt.Fprint(r.out, prompt)
+		line, err := r.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		r.addHistory(line)
+		return line, nil
+	}
+
+	restore, err := makeRaw(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	var buf []rune
+	pos := 0
+	histIndex := len(r.history)
+	r.redraw(prompt, buf, pos)
+
+	for {
+		c, _, err := r.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch c {
+		case '\r', '\n': // Enter
+			fmt.Fprint(r.out, "\r\n")
+			line := string(buf)
+			r.addHistory(line)
+			return line, nil
+		case 3: // Ctrl-C discards the current line
+			fmt.Fprint(r.out, "^C\r\n")
+			buf, pos, histIndex = nil, 0, len(r.history)
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(r.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(buf)
+		case 21: // Ctrl-U
+			buf, pos = buf[pos:], 0
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case '\t':
+			buf, pos = r.completeLine(prompt, buf, pos)
+		case 27: // Escape sequences (arrow keys, delete)
+			buf, pos, histIndex = r.handleEscape(buf, pos, histIndex)
+		default:
+			if c >= 32 {
+				buf = append(buf[:pos], append([]rune{c}, buf[pos:]...)...)
+				pos++
+			}
+		}
+		r.redraw(prompt, buf, pos)
+	}
+}
+
+// handleEscape processes an ANSI escape sequence after the ESC byte was read.
+func (r *lineReader) handleEscape(buf []rune, pos, histIndex int) ([]rune, int, int) {
+	if next, _, err := r.in.ReadRune(); err != nil || next != '[' {
+		return buf, pos, histIndex
+	}
+	code, _, err := r.in.ReadRune()
+	if err != nil {
+		return buf, pos, histIndex
+	}
+
+	switch code {
+	case 'A': // Up
+		if histIndex > 0 {
+			histIndex--
+			buf = []rune(r.history[histIndex])
+			pos = len(buf)
+		}
+	case 'B': // Down
+		if histIndex < len(r.history)-1 {
+			histIndex++
+			buf = []rune(r.history[histIndex])
+		} else {
+			histIndex = len(r.history)
+			buf = nil
+		}
+		pos = len(buf)
+	case 'C': // Right
+		if pos < len(buf) {
+			pos++
+		}
+	case 'D': // Left
+		if pos > 0 {
+			pos--
+		}
+	case 'H':
+		pos = 0
+	case 'F':
+		pos = len(buf)
+	case '3': // Delete, sent as ESC [ 3 ~
+		r.in.ReadRune()
+		if pos < len(buf) {
+			buf = append(buf[:pos], buf[pos+1:]...)
+		}
+	}
+	return buf, pos, histIndex
+}
+
+// completeLine applies tab completion to the text before the cursor. A single
+// candidate is inserted; several candidates are listed and their common
+// prefix is inserted.
+func (r *lineReader) completeLine(prompt string, buf []rune, pos int) ([]rune, int) {
+	if r.complete == nil {
+		return buf, pos
+	}
+	candidates := r.complete(string(buf[:pos]))
+	if len(candidates) == 0 {
+		return buf, pos
+	}
+
+	replacement := candidates[0] + " "
+	if len(candidates) > 1 {
+		fmt.Fprintf(r.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+		replacement = commonPrefix(candidates)
+	}
+
+	rest := buf[pos:]
+	buf = append([]rune(replacement), rest...)
+	return buf, len([]rune(replacement))
+}
+
+// redraw rewrites the current line and positions the cursor.
+func (r *lineReader) redraw(prompt string, buf []rune, pos int) {
+	fmt.Fprintf(r.out, "\r%s%s\x1b[K", prompt, string(buf))
+	if back := len(buf) - pos; back > 0 {
+		fmt.Fprintf(r.out, "\x1b[%dD", back)
+	}
+}
+
+// addHistory records a line unless it is empty or repeats the previous entry.
+func (r *lineReader) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(r.history); n > 0 && r.history[n-1] == line {
+		return
+	}
+	r.history = append(r.history, line)
+}
+
+// commonPrefix returns the longest prefix shared by all strings.
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -5,39 +5,68 @@ import (
 	"elastic-btree/internal/tree"
 	"elastic-btree/pkg/config"
 	"elastic-btree/pkg/logger"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 )
 
 func main() {
-	// Load configuration
-	cfg, err := config.Load()
+	// Load configuration; global flags come before the command.
+	cfg, args, err := config.Parse(os.Args[1:])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	os.Args = append(os.Args[:1], args...)
 
 	// Initialize logger
 	log := logger.New(cfg.LogLevel, os.Stderr)
+	if cfg.LogFormat == "json" {
+		log = logger.NewWithHandler(cfg.LogLevel, logger.NewJSONHandler(os.Stderr))
+	}
+
+	if len(os.Args) < 2 {
+		printUsage(log)
+		os.Exit(1)
+	}
+	if os.Args[1] == "config" {
+		handleConfig(cfg, log)
+		return
+	}
 
 	// Create tree and storage
 	//currentTree := tree.NewTree(cfg.TreeDegree, log)
 	storage := storage.NewStorage(cfg.StoragePath)
+	storage.SetSync(cfg.Fsync == "always")
 
 	// Load tree from disk (if it exists)
 	currentTree, err := storage.LoadTree()
-	if err != nil {
+	var unknownComparator *tree.UnknownComparatorError
+	if errors.As(err, &unknownComparator) {
+		// Starting over would overwrite the saved tree on the next save.
+		log.Errorf("Load failed: %v", err)
+		os.Exit(1)
+	} else if err != nil {
 		log.Infof("No existing tree found, creating a new one")
 		currentTree = tree.NewTree(cfg.TreeDegree, log)
+		if err := currentTree.SetComparator(cfg.Comparator); err != nil {
+			log.Errorf("Invalid comparator: %v", err)
+			os.Exit(1)
+		}
 	} else {
 		log.Infof("Tree loaded from disk")
 		// Re-inject dependencies that weren't serialized.
     	currentTree.Logger = log
+		// TREE_DEGREE only applies to new trees.
+		if currentTree.Degree != cfg.TreeDegree {
+			log.Warnf("Tree file has degree %d but TREE_DEGREE is %d; run \"redegree %d\" to rebuild it",
+				currentTree.Degree, cfg.TreeDegree, cfg.TreeDegree)
+		}
 	}
 
-	if len(os.Args) < 2 {
-		printUsage(log)
+	if err := applyRuntimeConfig(currentTree, log, cfg); err != nil {
+		log.Errorf("Invalid configuration: %v", err)
 		os.Exit(1)
 	}
 
@@ -54,9 +83,29 @@ func main() {
 	case "load":
 		currentTree = handleLoad(storage, cfg, log)
 	case "print":
-		currentTree.PrintTreeStructure()
+		handlePrint(currentTree, log)
 	case "validate":
 		handleValidate(currentTree, log)
+	case "stats":
+		handleStats(currentTree, log)
+	case "redegree":
+		handleRedegree(currentTree, log, storage)
+	case "shell":
+		runShell(currentTree, storage, log)
+	case "exec":
+		handleExec(currentTree, storage, log)
+	case "export":
+		handleExport(currentTree, log)
+	case "import":
+		handleImport(currentTree, storage, log)
+	case "serve":
+		handleServe(currentTree, storage, cfg, log)
+	case "serve-resp":
+		handleServeRESP(currentTree, storage, cfg, log)
+	case "follow":
+		handleFollow(currentTree, storage, cfg, log)
+	case "watch":
+		handleWatch(log)
 	default:
 		log.Errorf("Unknown command: %s", command)
 		printUsage(log)
@@ -65,15 +114,37 @@ func main() {
 }
 
 func printUsage(log *logger.Logger) {
-	log.Infof("Usage: ./main <command> [arguments]")
+	log.Infof("Usage: ./main [--config file] [--option value ...] <command> [arguments]")
 	log.Infof("Commands:")
 	log.Infof("  insert <key> <value> - Insert a key-value pair")
 	log.Infof("  delete <key>         - Delete a key")
 	log.Infof("  search <key>         - Search for a key")
 	log.Infof("  save                 - Save tree to disk")
 	log.Infof("  load                 - Load tree from disk")
-	log.Infof("  print                - Print tree structure")
+	log.Infof("  print [--format ascii|dot|json] [--highlight key[,key...]]")
+	log.Infof("                       - Print tree structure with node links and fill, marking search paths")
 	log.Infof("  validate             - Validate tree properties")
+	log.Infof("  stats [--format table|json]")
+	log.Infof("                       - Print tree shape and rebalancing statistics")
+	log.Infof("  redegree <degree>    - Rebuild the tree with a new degree and save it")
+	log.Infof("  shell                - Start an interactive session")
+	log.Infof("  exec [--continue] [--format text|ndjson] [script|-]")
+	log.Infof("                       - Run a script of commands and save once")
+	log.Infof("  export [--format csv|ndjson|json] [--output file]")
+	log.Infof("                       - Write all pairs in key order")
+	log.Infof("  import [--format csv|ndjson|json] [--key-column name] [--value-column name]")
+	log.Infof("         [--no-header] [--on-duplicate skip|overwrite|error] [--batch-size N] [file|-]")
+	log.Infof("                       - Read pairs into the tree")
+	log.Infof("  serve [--addr host:port] [--checkpoint interval] [--replicate host:port] [--retain N]")
+	log.Infof("                       - Serve the tree over HTTP")
+	log.Infof("  serve-resp [--addr host:port] [--checkpoint interval] [--replicate host:port] [--retain N]")
+	log.Infof("                       - Serve the tree over the Redis protocol")
+	log.Infof("  follow --leader host:port [--addr host:port]")
+	log.Infof("                       - Replicate a leader and serve it read-only over HTTP")
+	log.Infof("  watch [--server url] [--from N] [--to N]")
+	log.Infof("                       - Stream changes from a running server as NDJSON")
+	log.Infof("  config show [--format table|json]")
+	log.Infof("                       - Print the effective configuration and where each value came from")
 }
 
 func handleInsert(t *tree.Tree, log *logger.Logger, s *storage.Storage) {
@@ -167,6 +238,31 @@ func handleLoad(s *storage.Storage, cfg *config.Config, log *logger.Logger) *tre
 	return loadedTree
 }
 
+func handleRedegree(t *tree.Tree, log *logger.Logger, s *storage.Storage) {
+	if len(os.Args) < 3 {
+		log.Errorf("Redegree command requires a degree")
+		os.Exit(1)
+	}
+
+	degree, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		log.Errorf("Invalid degree: %v", err)
+		os.Exit(1)
+	}
+
+	if err := t.Rebuild(degree); err != nil {
+		log.Errorf("Rebuild failed: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("Rebuilt tree with degree %d (height %d)", t.Degree, t.Height)
+
+	if err := s.SaveTree(t); err != nil {
+		log.Errorf("Save failed: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("Tree saved successfully")
+}
+
 func handleValidate(t *tree.Tree, log *logger.Logger) {
 	if valid := t.ValidateTree(); valid {
 		log.Infof("Tree validation successful")
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// handlePrint writes the tree's structure with parent-child links and fill
+// levels, optionally marking the search path of some keys.
+//
+// Usage: print [--format ascii|dot|json] [--highlight key[,key...]]
+func handlePrint(t *tree.Tree, log *logger.Logger) {
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	format := fs.String("format", "ascii", "output format: ascii, dot (Graphviz) or json")
+	highlightList := fs.String("highlight", "", "comma-separated keys whose search paths are marked")
+	fs.Parse(os.Args[2:])
+
+	var highlight []int
+	if *highlightList != "" {
+		for _, s := range strings.Split(*highlightList, ",") {
+			key, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				log.Errorf("Invalid highlight key: %s", s)
+				os.Exit(1)
+			}
+			highlight = append(highlight, key)
+		}
+	}
+
+	structure := t.Structure(highlight...)
+	var err error
+	switch *format {
+	case "ascii":
+		err = structure.WriteASCII(os.Stdout)
+	case "dot":
+		err = structure.WriteDOT(os.Stdout)
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(structure)
+	default:
+		err = fmt.Errorf("unknown format %q (want ascii, dot or json)", *format)
+	}
+	if err != nil {
+		log.Errorf("Print failed: %v", err)
+		os.Exit(1)
+	}
+}
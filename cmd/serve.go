@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"elastic-btree/internal/replication"
+	"elastic-btree/internal/server"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/config"
+	"elastic-btree/pkg/logger"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serveOptions holds the flags shared by serve and serve-resp.
+type serveOptions struct {
+	addr       string
+	checkpoint time.Duration
+	pinned     bool   // --checkpoint was given, so config reloads keep it
+	replicate  string // Address for replication followers; empty disables
+	retain     int    // Log entries kept for reconnecting followers
+}
+
+// parseServeFlags parses the flags of a server subcommand.
+// Defaults come from the configuration.
+func parseServeFlags(name, defaultAddr string, cfg *config.Config) serveOptions {
+	var opts serveOptions
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&opts.addr, "addr", defaultAddr, "address to listen on")
+	fs.DurationVar(&opts.checkpoint, "checkpoint", cfg.Checkpoint, "interval between saves of a modified tree (0 disables)")
+	fs.StringVar(&opts.replicate, "replicate", cfg.ReplicateAddr, "address to accept replication followers on (empty disables)")
+	fs.IntVar(&opts.retain, "retain", replication.DefaultRetain, "operation log entries kept for reconnecting followers")
+	fs.Parse(os.Args[2:])
+	fs.Visit(func(f *flag.Flag) {
+		opts.pinned = opts.pinned || f.Name == "checkpoint"
+	})
+	return opts
+}
+
+// newServer creates the server and, when requested, starts a replication
+// leader that records every mutation the server makes.
+func newServer(ctx context.Context, t *tree.Tree, s *storage.Storage, log *logger.Logger, opts serveOptions) *server.Server {
+	srv := server.New(t, s, log)
+	if opts.replicate == "" {
+		return srv
+	}
+
+	leader := replication.NewLeader(t, log, opts.retain)
+	srv.SetWriter(leader)
+	srv.SetReplicationStatus(func() interface{} {
+		return map[string]interface{}{
+			"role":      "leader",
+			"seq":       leader.Seq(),
+			"followers": leader.Followers(),
+		}
+	})
+	go func() {
+		if err := leader.ListenAndServe(ctx, opts.replicate); err != nil {
+			log.Errorf("Replication leader failed: %v", err)
+			os.Exit(1)
+		}
+	}()
+	return srv
+}
+
+// configPollInterval is how often a server checks its config file for changes.
+const configPollInterval = 2 * time.Second
+
+// watchConfig reloads the configuration when its file changes or the process
+// receives SIGHUP, applying the options that can change at runtime to the
+// tree, logger and server until ctx is cancelled. The checkpoint option only
+// applies if the interval was not given with --checkpoint.
+func watchConfig(ctx context.Context, cfg *config.Config, t *tree.Tree, srv *server.Server, log *logger.Logger, opts serveOptions) {
+	watcher := config.NewWatcher(cfg, log)
+	checkpoint := opts.checkpoint
+	watcher.OnChange(func(cfg *config.Config) {
+		if err := applyRuntimeConfig(t, log, cfg); err != nil {
+			log.Errorf("Config reload: keeping the tree's settings: %v", err)
+		}
+		if !opts.pinned && cfg.Checkpoint != checkpoint {
+			checkpoint = cfg.Checkpoint
+			srv.SetCheckpoint(checkpoint)
+		}
+	})
+	go watcher.Run(ctx, configPollInterval)
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hangup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				log.Infof("Received SIGHUP, reloading config")
+				watcher.Reload()
+			}
+		}
+	}()
+}
+
+// handleServe runs the HTTP REST server until SIGINT or SIGTERM, then saves
+// the tree and exits.
+//
+// Usage: serve [--addr host:port] [--checkpoint interval] [--replicate host:port] [--retain N]
+func handleServe(t *tree.Tree, s *storage.Storage, cfg *config.Config, log *logger.Logger) {
+	opts := parseServeFlags("serve", cfg.HTTPAddr, cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := newServer(ctx, t, s, log, opts)
+	watchConfig(ctx, cfg, t, srv, log, opts)
+	if err := srv.ListenAndServe(ctx, opts.addr, opts.checkpoint); err != nil {
+		log.Errorf("Server failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+// handleServeRESP runs the Redis-compatible RESP server until SIGINT or
+// SIGTERM, then saves the tree and exits.
+//
+// Usage: serve-resp [--addr host:port] [--checkpoint interval] [--replicate host:port] [--retain N]
+func handleServeRESP(t *tree.Tree, s *storage.Storage, cfg *config.Config, log *logger.Logger) {
+	opts := parseServeFlags("serve-resp", cfg.RESPAddr, cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := newServer(ctx, t, s, log, opts)
+	watchConfig(ctx, cfg, t, srv, log, opts)
+	if err := srv.ListenAndServeRESP(ctx, opts.addr, opts.checkpoint); err != nil {
+		log.Errorf("Server failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+// handleFollow replicates a leader into the local tree and serves it
+// read-only over HTTP. The tree is saved to the local storage path on exit.
+// A follower never evicts on its own: it applies the leader's evictions, so
+// size limits are rejected in the configuration and cleared from the tree.
+//
+// Usage: follow --leader host:port [--addr host:port]
+func handleFollow(t *tree.Tree, s *storage.Storage, cfg *config.Config, log *logger.Logger) {
+	fs := flag.NewFlagSet("follow", flag.ExitOnError)
+	leaderAddr := fs.String("leader", "", "replication address of the leader")
+	addr := fs.String("addr", ":8081", "address to serve the read-only HTTP API on")
+	fs.Parse(os.Args[2:])
+
+	if *leaderAddr == "" {
+		log.Errorf("follow requires --leader")
+		os.Exit(1)
+	}
+	if cfg.MaxEntries > 0 || cfg.MaxBytes > 0 || cfg.Eviction != "" {
+		log.Errorf("follow does not accept MAX_ENTRIES, MAX_BYTES or EVICTION_POLICY: a follower applies the leader's evictions")
+		os.Exit(1)
+	}
+	settings := t.Settings()
+	if settings.MaxEntries > 0 || settings.MaxBytes > 0 {
+		log.Warnf("Ignoring the size limits saved with the tree while following")
+		settings.MaxEntries, settings.MaxBytes = 0, 0
+		if err := t.Configure(settings); err != nil {
+			log.Errorf("Invalid configuration: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	follower := replication.NewFollower(*leaderAddr, t, log)
+	go follower.Run(ctx)
+
+	srv := server.New(t, s, log)
+	srv.SetReadOnly(true)
+	srv.SetReplicationStatus(func() interface{} {
+		return map[string]interface{}{
+			"role":     "follower",
+			"follower": follower.State(),
+		}
+	})
+	// ListenAndServe saves the tree on shutdown.
+	if err := srv.ListenAndServe(ctx, *addr, 0); err != nil {
+		log.Errorf("Server failed: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("Replicated to seq %d", follower.State().Applied)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// shellCommands lists the words offered by tab completion.
+var shellCommands = []string{
+	"insert", "delete", "search", "print", "validate",
+	"begin", "commit", "rollback", "history", "help",
+	".save", ".timing", ".exit", ".quit",
+}
+
+// shell is an interactive prompt that keeps one tree in memory. Changes are
+// only written to disk by the .save meta-command.
+type shell struct {
+	tree     *tree.Tree
+	storage  *storage.Storage
+	log      *logger.Logger
+	reader   *lineReader
+	out      io.Writer
+	backup   *tree.Tree // Snapshot taken by begin, restored by rollback
+	timing   bool
+	dirty    bool
+	wasDirty bool // Value of dirty when begin was run
+}
+
+// runShell starts the interactive prompt and returns when the user exits.
+func runShell(t *tree.Tree, s *storage.Storage, log *logger.Logger) {
+	sh := &shell{
+		tree:    t,
+		storage: s,
+		log:     log,
+		out:     os.Stdout,
+		timing:  true,
+	}
+	sh.reader = newLineReader(os.Stdin, os.Stdout, completeCommand)
+
+	fmt.Fprintln(sh.out, "Elastic B-Tree shell. Type \"help\" for commands.")
+	for {
+		line, err := sh.reader.ReadLine(sh.prompt())
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Errorf("Read failed: %v", err)
+			}
+			sh.exit()
+			return
+		}
+
+		args, err := splitArgs(line)
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == ".exit" || args[0] == ".quit" || args[0] == "exit" || args[0] == "quit" {
+			sh.exit()
+			return
+		}
+
+		start := time.Now()
+		if err := sh.execute(args); err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+		}
+		if sh.timing {
+			fmt.Fprintf(sh.out, "(%s)\n", time.Since(start))
+		}
+	}
+}
+
+// prompt returns the prompt string, marking open transactions and unsaved changes.
+func (sh *shell) prompt() string {
+	prompt := "btree"
+	if sh.backup != nil {
+		prompt += "[txn]"
+	}
+	if sh.dirty {
+		prompt += "*"
+	}
+	return prompt + "> "
+}
+
+// execute runs a single shell command.
+func (sh *shell) execute(args []string) error {
+	switch args[0] {
+	case "insert", "delete", "search":
+		result, mutated, err := runTreeCommand(sh.tree, args)
+		if err != nil {
+			return err
+		}
+		if mutated {
+			sh.dirty = true
+		}
+		fmt.Fprintln(sh.out, result.Text())
+	case "print":
+		fmt.Fprint(sh.out, sh.tree.ToString())
+	case "validate":
+		if !sh.tree.ValidateTree() {
+			return errors.New("tree validation failed")
+		}
+		fmt.Fprintln(sh.out, "Tree validation successful")
+	case "begin":
+		if sh.backup != nil {
+			return errors.New("transaction already open")
+		}
+		sh.backup = sh.tree.Clone()
+		sh.wasDirty = sh.dirty
+		fmt.Fprintln(sh.out, "Transaction started")
+	case "commit":
+		if sh.backup == nil {
+			return errors.New("no open transaction")
+		}
+		sh.backup = nil
+		fmt.Fprintln(sh.out, "Transaction committed")
+	case "rollback":
+		if sh.backup == nil {
+			return errors.New("no open transaction")
+		}
+		// Restore keeps the tree's own settings and subscribers.
+		sh.tree.Restore(sh.backup)
+		sh.backup = nil
+		sh.dirty = sh.wasDirty
+		fmt.Fprintln(sh.out, "Transaction rolled back")
+	case "history":
+		for i, line := range sh.reader.history {
+			fmt.Fprintf(sh.out, "%4d  %s\n", i+1, line)
+		}
+	case "help":
+		sh.printHelp()
+	case ".save":
+		if sh.backup != nil {
+			return errors.New("commit or rollback the open transaction before saving")
+		}
+		if err := sh.storage.SaveTree(sh.tree); err != nil {
+			return fmt.Errorf("save failed: %v", err)
+		}
+		sh.dirty = false
+		fmt.Fprintln(sh.out, "Tree saved successfully")
+	case ".timing":
+		if len(args) < 2 || (args[1] != "on" && args[1] != "off") {
+			return errors.New("usage: .timing on|off")
+		}
+		sh.timing = args[1] == "on"
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	return nil
+}
+
+// exit warns about work that is about to be discarded.
+func (sh *shell) exit() {
+	if sh.backup != nil {
+		fmt.Fprintln(sh.out, "Open transaction discarded")
+	}
+	if sh.dirty {
+		fmt.Fprintln(sh.out, "Unsaved changes discarded (use .save to persist)")
+	}
+}
+
+func (sh *shell) printHelp() {
+	fmt.Fprintln(sh.out, "Commands:")
+	fmt.Fprintln(sh.out, "  insert <key> <value> - Insert a key-value pair")
+	fmt.Fprintln(sh.out, "  delete <key>         - Delete a key")
+	fmt.Fprintln(sh.out, "  search <key>         - Search for a key")
+	fmt.Fprintln(sh.out, "  print                - Print tree structure")
+	fmt.Fprintln(sh.out, "  validate             - Validate tree properties")
+	fmt.Fprintln(sh.out, "  begin                - Start a transaction")
+	fmt.Fprintln(sh.out, "  commit               - Keep the changes made since begin")
+	fmt.Fprintln(sh.out, "  rollback             - Discard the changes made since begin")
+	fmt.Fprintln(sh.out, "  history              - List previous commands")
+	fmt.Fprintln(sh.out, "Meta-commands:")
+	fmt.Fprintln(sh.out, "  .save                - Save tree to disk")
+	fmt.Fprintln(sh.out, "  .timing on|off       - Toggle timing output")
+	fmt.Fprintln(sh.out, "  .exit, .quit         - Leave the shell")
+}
+
+// completeCommand returns the commands matching the first word of a line.
+func completeCommand(line string) []string {
+	if strings.ContainsAny(line, " \t") {
+		return nil
+	}
+	var matches []string
+	for _, cmd := range shellCommands {
+		if strings.HasPrefix(cmd, line) {
+			matches = append(matches, cmd)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// splitArgs splits a command line into words, honouring single and double
+// quotes. A backslash outside single quotes takes the next character
+// literally, so a value can contain quotes of either kind.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+
+	for _, c := range line {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(c)
+			inWord = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestShell(t *testing.T) (*shell, *bytes.Buffer) {
+	var out bytes.Buffer
+	log := logger.New(logger.Error, io.Discard)
+	sh := &shell{
+		tree:    tree.NewTree(3, log),
+		storage: storage.NewStorage(filepath.Join(t.TempDir(), "tree.json")),
+		log:     log,
+		reader:  &lineReader{},
+		out:     &out,
+	}
+	return sh, &out
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr string
+	}{
+		{line: "", want: nil},
+		{line: "   \t ", want: nil},
+		{line: "insert 1 one", want: []string{"insert", "1", "one"}},
+		{line: "  insert\t1   one  ", want: []string{"insert", "1", "one"}},
+		{line: `insert 1 "hello world"`, want: []string{"insert", "1", "hello world"}},
+		{line: `insert 1 'hello world'`, want: []string{"insert", "1", "hello world"}},
+		{line: `insert 1 ""`, want: []string{"insert", "1", ""}},
+		{line: `insert 1 a"b c"d`, want: []string{"insert", "1", "ab cd"}},
+		{line: `insert 1 "it's"`, want: []string{"insert", "1", "it's"}},
+		{line: `insert 1 'say "hi"'`, want: []string{"insert", "1", `say "hi"`}},
+		{line: `insert 1 hello\ world`, want: []string{"insert", "1", "hello world"}},
+		{line: `insert 1 \"quoted\"`, want: []string{"insert", "1", `"quoted"`}},
+		{line: `insert 1 "a \"b\" c"`, want: []string{"insert", "1", `a "b" c`}},
+		{line: `insert 1 'a\b'`, want: []string{"insert", "1", `a\b`}},
+		{line: `insert 1 a\\b`, want: []string{"insert", "1", `a\b`}},
+		{line: `insert 1 \'`, want: []string{"insert", "1", "'"}},
+		{line: `insert 1 "open`, wantErr: "unterminated quote"},
+		{line: `insert 1 'open`, wantErr: "unterminated quote"},
+		{line: `insert 1 "a\"`, wantErr: "unterminated quote"},
+		{line: `insert 1 a\`, wantErr: "trailing backslash"},
+	}
+
+	for _, tt := range tests {
+		got, err := splitArgs(tt.line)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("splitArgs(%q) error = %v, want %q", tt.line, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitArgs(%q) unexpected error: %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "ins", want: []string{"insert"}},
+		{line: "insert", want: []string{"insert"}},
+		{line: "h", want: []string{"help", "history"}},
+		{line: ".", want: []string{".exit", ".quit", ".save", ".timing"}},
+		{line: "x", want: nil},
+		{line: "insert ", want: nil},
+		{line: "insert 1", want: nil},
+		{line: "s\t", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := completeCommand(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("completeCommand(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	if got := completeCommand(""); len(got) != len(shellCommands) {
+		t.Errorf("completeCommand(\"\") returned %d commands, want %d", len(got), len(shellCommands))
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{values: []string{"insert"}, want: "insert"},
+		{values: []string{"help", "history"}, want: "h"},
+		{values: []string{".save", ".timing"}, want: "."},
+		{values: []string{"delete", "search"}, want: ""},
+		{values: []string{"commit", "commit"}, want: "commit"},
+		{values: []string{"roll", "rollback"}, want: "roll"},
+	}
+
+	for _, tt := range tests {
+		if got := commonPrefix(tt.values); got != tt.want {
+			t.Errorf("commonPrefix(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestRunTreeCommand(t *testing.T) {
+	tests := []struct {
+		args        []string
+		wantText    string
+		wantMutated bool
+		wantErr     string
+	}{
+		{args: []string{"search", "1"}, wantText: "Key 1 not found"},
+		{args: []string{"insert", "1", "one"}, wantText: "Inserted key 1", wantMutated: true},
+		{args: []string{"search", "1"}, wantText: "Found key 1: one"},
+		{args: []string{"insert", "-5", "minus"}, wantText: "Inserted key -5", wantMutated: true},
+		{args: []string{"delete", "1"}, wantText: "Deleted key 1", wantMutated: true},
+		{args: []string{"delete", "1"}, wantText: "Key 1 not found"},
+		{args: []string{"search", "-5"}, wantText: "Found key -5: minus"},
+		{args: []string{"insert", "1"}, wantErr: "usage: insert <key> <value>"},
+		{args: []string{"delete"}, wantErr: "usage: delete <key>"},
+		{args: []string{"search"}, wantErr: "usage: search <key>"},
+		{args: []string{"insert", "one", "1"}, wantErr: "invalid key"},
+		{args: []string{"search", "1.5"}, wantErr: "invalid key"},
+		{args: []string{"print"}, wantErr: "unknown command: print"},
+	}
+
+	tr := newImportTree()
+	for _, tt := range tests {
+		result, mutated, err := runTreeCommand(tr, tt.args)
+		if tt.wantErr != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("runTreeCommand(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+			if mutated {
+				t.Errorf("runTreeCommand(%q) reported a change on error", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("runTreeCommand(%q) unexpected error: %v", tt.args, err)
+			continue
+		}
+		if got := result.Text(); got != tt.wantText {
+			t.Errorf("runTreeCommand(%q) text = %q, want %q", tt.args, got, tt.wantText)
+		}
+		if mutated != tt.wantMutated {
+			t.Errorf("runTreeCommand(%q) mutated = %v, want %v", tt.args, mutated, tt.wantMutated)
+		}
+	}
+
+	if !tr.ValidateTree() {
+		t.Fatal("tree is invalid after commands")
+	}
+	if got, want := treePairs(tr), map[int]string{-5: "minus"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tree contents = %v, want %v", got, want)
+	}
+}
+
+func TestShellTransactions(t *testing.T) {
+	tests := []struct {
+		name       string
+		commands   []string
+		wantPrompt string
+		wantPairs  map[int]string
+		wantErr    string // Error expected from the last command
+	}{
+		{
+			name:       "insert marks dirty",
+			commands:   []string{"insert 1 one"},
+			wantPrompt: "btree*> ",
+			wantPairs:  map[int]string{1: "one"},
+		},
+		{
+			name:       "search leaves clean",
+			commands:   []string{"search 1", "delete 1"},
+			wantPrompt: "btree> ",
+			wantPairs:  map[int]string{},
+		},
+		{
+			name:       "begin shows transaction",
+			commands:   []string{"begin"},
+			wantPrompt: "btree[txn]> ",
+			wantPairs:  map[int]string{},
+		},
+		{
+			name:       "commit keeps changes",
+			commands:   []string{"begin", "insert 1 one", "insert 2 two", "commit"},
+			wantPrompt: "btree*> ",
+			wantPairs:  map[int]string{1: "one", 2: "two"},
+		},
+		{
+			name:       "rollback discards changes",
+			commands:   []string{"begin", "insert 1 one", "insert 2 two", "rollback"},
+			wantPrompt: "btree> ",
+			wantPairs:  map[int]string{},
+		},
+		{
+			name:       "rollback restores earlier dirty state",
+			commands:   []string{"insert 1 one", "begin", "delete 1", "insert 2 two", "rollback"},
+			wantPrompt: "btree*> ",
+			wantPairs:  map[int]string{1: "one"},
+		},
+		{
+			name:       "save clears dirty",
+			commands:   []string{"insert 1 one", ".save"},
+			wantPrompt: "btree> ",
+			wantPairs:  map[int]string{1: "one"},
+		},
+		{
+			name:       "rollback after save returns to clean",
+			commands:   []string{"insert 1 one", ".save", "begin", "insert 2 two", "rollback"},
+			wantPrompt: "btree> ",
+			wantPairs:  map[int]string{1: "one"},
+		},
+		{
+			name:       "save refused inside transaction",
+			commands:   []string{"begin", "insert 1 one", ".save"},
+			wantPrompt: "btree[txn]*> ",
+			wantPairs:  map[int]string{1: "one"},
+			wantErr:    "commit or rollback the open transaction before saving",
+		},
+		{
+			name:       "nested begin refused",
+			commands:   []string{"begin", "begin"},
+			wantPrompt: "btree[txn]> ",
+			wantPairs:  map[int]string{},
+			wantErr:    "transaction already open",
+		},
+		{
+			name:       "commit without begin",
+			commands:   []string{"commit"},
+			wantPrompt: "btree> ",
+			wantPairs:  map[int]string{},
+			wantErr:    "no open transaction",
+		},
+		{
+			name:       "rollback without begin",
+			commands:   []string{"insert 1 one", "rollback"},
+			wantPrompt: "btree*> ",
+			wantPairs:  map[int]string{1: "one"},
+			wantErr:    "no open transaction",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, _ := newTestShell(t)
+			for i, line := range tt.commands {
+				args, err := splitArgs(line)
+				if err != nil {
+					t.Fatalf("splitArgs(%q): %v", line, err)
+				}
+				err = sh.execute(args)
+				if i == len(tt.commands)-1 && tt.wantErr != "" {
+					if err == nil || err.Error() != tt.wantErr {
+						t.Fatalf("%q error = %v, want %q", line, err, tt.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("%q: %v", line, err)
+				}
+			}
+
+			if got := sh.prompt(); got != tt.wantPrompt {
+				t.Errorf("prompt = %q, want %q", got, tt.wantPrompt)
+			}
+			if got := treePairs(sh.tree); !reflect.DeepEqual(got, tt.wantPairs) {
+				t.Errorf("tree contents = %v, want %v", got, tt.wantPairs)
+			}
+			if !sh.tree.ValidateTree() {
+				t.Error("tree is invalid")
+			}
+		})
+	}
+}
+
+func TestShellRollbackKeepsTree(t *testing.T) {
+	sh, _ := newTestShell(t)
+	original := sh.tree
+	original.DebugComparator = true
+	var events []string
+	original.OnChange(func(e tree.ChangeEvent) {
+		events = append(events, e.Op)
+	})
+
+	for _, line := range []string{"insert 1 one", "begin", "insert 2 two", "rollback", "insert 3 three"} {
+		args, _ := splitArgs(line)
+		if err := sh.execute(args); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+	}
+
+	if sh.tree != original {
+		t.Fatal("rollback replaced the shell's tree")
+	}
+	if !sh.tree.DebugComparator {
+		t.Error("rollback dropped DebugComparator")
+	}
+	if got, want := treePairs(sh.tree), map[int]string{1: "one", 3: "three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tree contents = %v, want %v", got, want)
+	}
+	if len(events) != 4 || events[2] != tree.OpReset || events[3] != tree.OpInsert {
+		t.Errorf("change events = %v, want insert, insert, reset, insert", events)
+	}
+}
+
+func TestShellSaveWritesTree(t *testing.T) {
+	sh, out := newTestShell(t)
+	for _, line := range []string{"insert 1 one", "insert 2 two", ".save"} {
+		args, _ := splitArgs(line)
+		if err := sh.execute(args); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+	}
+	if !strings.Contains(out.String(), "Tree saved successfully") {
+		t.Errorf("output = %q, want save confirmation", out.String())
+	}
+
+	loaded, err := sh.storage.LoadTree()
+	if err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	if got, want := treePairs(loaded), map[int]string{1: "one", 2: "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("saved contents = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// handleStats prints the tree's statistics.
+//
+// Usage: stats [--format table|json]
+func handleStats(t *tree.Tree, log *logger.Logger) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	fs.Parse(os.Args[2:])
+
+	var err error
+	switch *format {
+	case "table":
+		err = writeStatsTable(os.Stdout, t.Stats())
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(t.Stats())
+	default:
+		err = fmt.Errorf("unknown format %q (want table or json)", *format)
+	}
+	if err != nil {
+		log.Errorf("Stats failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+// writeStatsTable writes stats as aligned name/value rows.
+func writeStatsTable(w io.Writer, stats tree.Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Size\t%d\n", stats.Size)
+	fmt.Fprintf(tw, "Height\t%d\n", stats.Height)
+	fmt.Fprintf(tw, "Degree\t%d\n", stats.Degree)
+	fmt.Fprintf(tw, "Nodes\t%d (%d leaves, %d internal)\n", stats.Nodes, stats.Leaves, stats.Internal)
+	for level, count := range stats.LevelNodes {
+		fmt.Fprintf(tw, "  Level %d\t%d\n", level, count)
+	}
+	fmt.Fprintf(tw, "Fill factor\t%.1f%%\n", stats.FillFactor*100)
+	for bucket, count := range stats.FillHistogram {
+		low := bucket * 100 / tree.FillBuckets
+		fmt.Fprintf(tw, "  %d-%d%%\t%d\n", low, low+100/tree.FillBuckets, count)
+	}
+	fmt.Fprintf(tw, "Bytes (estimated)\t%d\n", stats.Bytes)
+	fmt.Fprintf(tw, "Splits\t%d\n", stats.Splits)
+	fmt.Fprintf(tw, "Merges\t%d\n", stats.Merges)
+	fmt.Fprintf(tw, "Borrows\t%d\n", stats.Borrows)
+	return tw.Flush()
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// record is a single key-value pair read by import or written by export.
+type record struct {
+	Key   int         `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// handleExport streams all pairs in key order to stdout or a file.
+//
+// Usage: export [--format csv|ndjson|json] [--output file]
+func handleExport(t *tree.Tree, log *logger.Logger) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "csv", "output format: csv, ndjson or json")
+	output := fs.String("output", "", "output file (default stdout)")
+	fs.Parse(os.Args[2:])
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Errorf("Failed to create output file: %v", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	w := bufio.NewWriter(out)
+	count, err := exportRecords(t, w, *format)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Errorf("Export failed: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("Exported %d records", count)
+}
+
+// exportRecords writes every pair in key order using the given format.
+func exportRecords(t *tree.Tree, w io.Writer, format string) (int, error) {
+	count := 0
+	var writeErr error
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"key", "value"})
+		t.Ascend(func(key int, value interface{}) bool {
+			writeErr = cw.Write([]string{strconv.Itoa(key), fmt.Sprint(value)})
+			count++
+			return writeErr == nil
+		})
+		cw.Flush()
+		if writeErr == nil {
+			writeErr = cw.Error()
+		}
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		t.Ascend(func(key int, value interface{}) bool {
+			writeErr = encoder.Encode(record{Key: key, Value: value})
+			count++
+			return writeErr == nil
+		})
+	case "json":
+		// Stream the array element by element rather than building it in memory.
+		io.WriteString(w, "[")
+		t.Ascend(func(key int, value interface{}) bool {
+			var data []byte
+			data, writeErr = json.Marshal(record{Key: key, Value: value})
+			if writeErr != nil {
+				return false
+			}
+			if count > 0 {
+				io.WriteString(w, ",")
+			}
+			io.WriteString(w, "\n  ")
+			_, writeErr = w.Write(data)
+			count++
+			return writeErr == nil
+		})
+		if count > 0 {
+			io.WriteString(w, "\n")
+		}
+		_, err := io.WriteString(w, "]\n")
+		if writeErr == nil {
+			writeErr = err
+		}
+	default:
+		return 0, fmt.Errorf("invalid format: %s (must be csv, ndjson or json)", format)
+	}
+	return count, writeErr
+}
+
+// importOptions controls how import applies records to the tree.
+type importOptions struct {
+	format      string // csv, ndjson or json
+	keyColumn   string // CSV column or JSON field holding the key
+	valueColumn string // CSV column or JSON field holding the value
+	noHeader    bool   // CSV input has no header row; columns are 0-based indexes
+	onDuplicate string // skip, overwrite or error
+	batchSize   int    // Records inserted between saves (0 saves once at the end); unused by the bulk loader
+}
+
+// handleImport reads pairs from a file or stdin and adds them to the tree.
+// The whole input is read into memory first, so it can be checked for order.
+// Input that is strictly ascending and targets an empty tree goes through the
+// bulk loader, which builds the tree in one pass and saves it once, ignoring
+// --batch-size; everything else is inserted in batches. With --no-header the
+// key and value columns default to 0 and 1.
+//
+// Usage: import [--format csv|ndjson|json] [--key-column name] [--value-column name]
+//
+//	[--no-header] [--on-duplicate skip|overwrite|error] [--batch-size N] [file|-]
+func handleImport(t *tree.Tree, s *storage.Storage, log *logger.Logger) {
+	opts, path, err := parseImportOptions(os.Args[2:])
+	if err != nil {
+		log.Errorf("Import failed: %v", err)
+		os.Exit(1)
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Errorf("Failed to open input: %v", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	records, err := readRecords(bufio.NewReader(in), opts)
+	if err != nil {
+		log.Errorf("Import failed: %v", err)
+		os.Exit(1)
+	}
+
+	if err := importRecords(t, s, records, opts, log); err != nil {
+		log.Errorf("Import failed: %v", err)
+		os.Exit(1)
+	}
+	if err := s.SaveTree(t); err != nil {
+		log.Errorf("Save failed: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("Tree saved successfully")
+}
+
+// parseImportOptions parses the import flags and returns them with the input
+// path (empty or "-" for stdin).
+func parseImportOptions(args []string) (importOptions, string, error) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	opts := importOptions{}
+	fs.StringVar(&opts.format, "format", "csv", "input format: csv, ndjson or json")
+	fs.StringVar(&opts.keyColumn, "key-column", "key", "CSV column or JSON field holding the key (0 with --no-header)")
+	fs.StringVar(&opts.valueColumn, "value-column", "value", "CSV column or JSON field holding the value (1 with --no-header)")
+	fs.BoolVar(&opts.noHeader, "no-header", false, "CSV input has no header; columns are 0-based indexes")
+	fs.StringVar(&opts.onDuplicate, "on-duplicate", "skip", "duplicate key handling: skip, overwrite or error")
+	fs.IntVar(&opts.batchSize, "batch-size", 0, "records inserted between saves (0 saves once at the end; the bulk loader always saves once)")
+	fs.Parse(args)
+
+	if opts.noHeader {
+		// Header names mean nothing without a header, so fall back to the
+		// first two columns unless they were given explicitly.
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["key-column"] {
+			opts.keyColumn = "0"
+		}
+		if !set["value-column"] {
+			opts.valueColumn = "1"
+		}
+	}
+
+	if opts.onDuplicate != "skip" && opts.onDuplicate != "overwrite" && opts.onDuplicate != "error" {
+		return opts, "", fmt.Errorf("invalid --on-duplicate: %s (must be skip, overwrite or error)", opts.onDuplicate)
+	}
+	if opts.batchSize < 0 {
+		return opts, "", fmt.Errorf("invalid --batch-size: %d (must be >= 0)", opts.batchSize)
+	}
+	return opts, fs.Arg(0), nil
+}
+
+// importRecords applies records to the tree, using the bulk loader when possible.
+func importRecords(t *tree.Tree, s *storage.Storage, records []record, opts importOptions, log *logger.Logger) error {
+	if t.Size == 0 && isStrictlyAscending(t, records) {
+		keys := make([]int, len(records))
+		values := make([]interface{}, len(records))
+		for i, r := range records {
+			keys[i], values[i] = r.Key, r.Value
+		}
+		if err := t.BulkLoad(keys, values); err != nil {
+			return err
+		}
+		log.Infof("Imported %d records using the bulk loader", len(records))
+		return nil
+	}
+
+	inserted, skipped := 0, 0
+	for i, r := range records {
+		if opts.onDuplicate == "overwrite" {
+			// Replace in place: one change event and no window without the key.
+			t.Put(r.Key, r.Value)
+		} else if _, found := t.Search(r.Key); found && opts.onDuplicate == "skip" {
+			skipped++
+			continue
+		} else if found {
+			return fmt.Errorf("duplicate key %d (record %d)", r.Key, i+1)
+		} else {
+			t.Insert(r.Key, r.Value)
+		}
+		inserted++
+
+		if opts.batchSize > 0 && (i+1)%opts.batchSize == 0 {
+			if err := s.SaveTree(t); err != nil {
+				return fmt.Errorf("save after record %d failed: %v", i+1, err)
+			}
+			log.Debugf("Import: saved batch ending at record %d", i+1)
+		}
+	}
+	log.Infof("Imported %d records (%d duplicates skipped)", inserted, skipped)
+	return nil
+}
+
+// isStrictlyAscending reports whether the records are ordered by the tree's
+// comparator with no duplicate keys.
+func isStrictlyAscending(t *tree.Tree, records []record) bool {
+	for i := 1; i < len(records); i++ {
+		if t.Comparator(records[i-1].Key, records[i].Key) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// readRecords decodes all records from the input in the requested format.
+func readRecords(in io.Reader, opts importOptions) ([]record, error) {
+	switch opts.format {
+	case "csv":
+		return readCSVRecords(in, opts)
+	case "ndjson":
+		var records []record
+		decoder := json.NewDecoder(in)
+		decoder.UseNumber()
+		for n := 1; ; n++ {
+			var obj map[string]interface{}
+			if err := decoder.Decode(&obj); err != nil {
+				if errors.Is(err, io.EOF) {
+					return records, nil
+				}
+				return nil, fmt.Errorf("record %d: %v", n, err)
+			}
+			r, err := objectRecord(obj, opts)
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %v", n, err)
+			}
+			records = append(records, r)
+		}
+	case "json":
+		var objs []map[string]interface{}
+		decoder := json.NewDecoder(in)
+		decoder.UseNumber()
+		if err := decoder.Decode(&objs); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %v", err)
+		}
+		records := make([]record, 0, len(objs))
+		for i, obj := range objs {
+			r, err := objectRecord(obj, opts)
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %v", i+1, err)
+			}
+			records = append(records, r)
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("invalid format: %s (must be csv, ndjson or json)", opts.format)
+	}
+}
+
+// readCSVRecords decodes CSV rows, locating the key and value columns by
+// header name or, with --no-header, by index.
+func readCSVRecords(in io.Reader, opts importOptions) ([]record, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+
+	keyIdx, valueIdx := -1, -1
+	if opts.noHeader {
+		var err error
+		if keyIdx, err = strconv.Atoi(opts.keyColumn); err != nil {
+			return nil, fmt.Errorf("--key-column must be an index with --no-header: %s", opts.keyColumn)
+		}
+		if valueIdx, err = strconv.Atoi(opts.valueColumn); err != nil {
+			return nil, fmt.Errorf("--value-column must be an index with --no-header: %s", opts.valueColumn)
+		}
+	} else {
+		header, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to read header: %v", err)
+		}
+		for i, name := range header {
+			if name == opts.keyColumn {
+				keyIdx = i
+			}
+			if name == opts.valueColumn {
+				valueIdx = i
+			}
+		}
+		if keyIdx < 0 {
+			return nil, fmt.Errorf("key column %q not found in header", opts.keyColumn)
+		}
+		if valueIdx < 0 {
+			return nil, fmt.Errorf("value column %q not found in header", opts.valueColumn)
+		}
+	}
+
+	var records []record
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if keyIdx >= len(row) || valueIdx >= len(row) {
+			return nil, fmt.Errorf("line %d: expected at least %d columns, got %d", line, max(keyIdx, valueIdx)+1, len(row))
+		}
+		key, err := strconv.Atoi(row[keyIdx])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid key: %v", line, err)
+		}
+		records = append(records, record{Key: key, Value: row[valueIdx]})
+	}
+}
+
+// objectRecord extracts a record from a decoded JSON object.
+func objectRecord(obj map[string]interface{}, opts importOptions) (record, error) {
+	rawKey, ok := obj[opts.keyColumn]
+	if !ok {
+		return record{}, fmt.Errorf("missing key field %q", opts.keyColumn)
+	}
+	number, ok := rawKey.(json.Number)
+	if !ok {
+		return record{}, fmt.Errorf("key field %q is not a number: %v", opts.keyColumn, rawKey)
+	}
+	key, err := strconv.Atoi(number.String())
+	if err != nil {
+		return record{}, fmt.Errorf("key field %q is not an integer: %v", opts.keyColumn, rawKey)
+	}
+	return record{Key: key, Value: obj[opts.valueColumn]}, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newImportTree() *tree.Tree {
+	return tree.NewTree(3, logger.New(logger.Error, io.Discard))
+}
+
+func treePairs(t *tree.Tree) map[int]string {
+	pairs := map[int]string{}
+	t.Ascend(func(key int, value interface{}) bool {
+		pairs[key] = fmt.Sprint(value)
+		return true
+	})
+	return pairs
+}
+
+func TestImportRoundTrip(t *testing.T) {
+	log := logger.New(logger.Error, io.Discard)
+	src := newImportTree()
+	for _, key := range []int{42, -7, 0, 1000, 3, 18, 99, -250} {
+		src.Insert(key, fmt.Sprintf("value %d", key))
+	}
+	want := treePairs(src)
+
+	for _, format := range []string{"csv", "ndjson", "json"} {
+		for _, batchSize := range []int{0, 3} {
+			t.Run(fmt.Sprintf("%s/batch=%d", format, batchSize), func(t *testing.T) {
+				var buf bytes.Buffer
+				count, err := exportRecords(src, &buf, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count != len(want) {
+					t.Errorf("exported %d records, want %d", count, len(want))
+				}
+
+				opts, _, err := parseImportOptions([]string{"--format", format, "--batch-size", fmt.Sprint(batchSize)})
+				if err != nil {
+					t.Fatal(err)
+				}
+				records, err := readRecords(&buf, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// An empty target takes the bulk loader; a non-empty one
+				// inserts record by record and saves every batch.
+				for _, seeded := range []bool{false, true} {
+					dst := newImportTree()
+					if seeded {
+						dst.Insert(42, "old")
+					}
+					s := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+					if err := importRecords(dst, s, records, opts, log); err != nil {
+						t.Fatal(err)
+					}
+					got := treePairs(dst)
+					if seeded {
+						got[42] = want[42] // skipped as a duplicate
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Fatalf("seeded=%v: imported %v, want %v", seeded, got, want)
+					}
+					if !dst.ValidateTree() {
+						t.Fatalf("seeded=%v: tree is invalid after import", seeded)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestImportNoHeader(t *testing.T) {
+	opts, path, err := parseImportOptions([]string{"--no-header", "pairs.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.keyColumn != "0" || opts.valueColumn != "1" || path != "pairs.csv" {
+		t.Fatalf("columns = %q, %q, path = %q", opts.keyColumn, opts.valueColumn, path)
+	}
+	records, err := readRecords(strings.NewReader("2,two\n1,one\n"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []record{{2, "two"}, {1, "one"}}; !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+
+	// Explicit columns still win.
+	opts, _, err = parseImportOptions([]string{"--no-header", "--key-column", "2", "--value-column", "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err = readRecords(strings.NewReader("a,x,5\n"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []record{{5, "a"}}; !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+}
+
+func TestImportOnDuplicate(t *testing.T) {
+	log := logger.New(logger.Error, io.Discard)
+	records := []record{{2, "new two"}, {5, "five"}, {1, "new one"}}
+
+	tests := []struct {
+		mode      string
+		wantPairs map[int]string
+		wantOps   []string
+		wantErr   string
+	}{
+		{
+			mode:      "overwrite",
+			wantPairs: map[int]string{1: "new one", 2: "new two", 3: "three", 5: "five"},
+			wantOps:   []string{tree.OpUpdate, tree.OpInsert, tree.OpUpdate},
+		},
+		{
+			mode:      "skip",
+			wantPairs: map[int]string{1: "one", 2: "two", 3: "three", 5: "five"},
+			wantOps:   []string{tree.OpInsert},
+		},
+		{
+			mode:      "error",
+			wantPairs: map[int]string{1: "one", 2: "two", 3: "three"},
+			wantErr:   "duplicate key 2 (record 1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			tr := newImportTree()
+			for key, value := range map[int]string{1: "one", 2: "two", 3: "three"} {
+				tr.Insert(key, value)
+			}
+			var ops []string
+			tr.OnChange(func(e tree.ChangeEvent) { ops = append(ops, e.Op) })
+
+			s := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+			err := importRecords(tr, s, records, importOptions{onDuplicate: tt.mode}, log)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := treePairs(tr); !reflect.DeepEqual(got, tt.wantPairs) {
+				t.Errorf("tree contents = %v, want %v", got, tt.wantPairs)
+			}
+			if !reflect.DeepEqual(ops, tt.wantOps) {
+				t.Errorf("change events = %v, want %v", ops, tt.wantOps)
+			}
+			if tr.Size != len(tt.wantPairs) || !tr.ValidateTree() {
+				t.Errorf("tree invalid after import (size %d)", tr.Size)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"elastic-btree/pkg/client"
+	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+// handleWatch prints change events from a running HTTP server as NDJSON until
+// interrupted. Changes are made by the server's process, so watching the
+// local tree file would never observe anything.
+//
+// Usage: watch [--server url] [--from N] [--to N]
+func handleWatch(log *logger.Logger) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	serverURL := fs.String("server", "http://localhost:8080", "base URL of the HTTP server")
+	fromFlag := fs.String("from", "", "lowest key to watch (default unbounded)")
+	toFlag := fs.String("to", "", "highest key to watch (default unbounded)")
+	fs.Parse(os.Args[2:])
+
+	from, to := math.MinInt, math.MaxInt
+	var err error
+	if *fromFlag != "" {
+		if from, err = strconv.Atoi(*fromFlag); err != nil {
+			log.Errorf("Invalid --from: %s", *fromFlag)
+			os.Exit(1)
+		}
+	}
+	if *toFlag != "" {
+		if to, err = strconv.Atoi(*toFlag); err != nil {
+			log.Errorf("Invalid --to: %s", *toFlag)
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := client.New(*serverURL, client.Options{})
+	defer c.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+	err = c.Watch(ctx, from, to, func(event client.Event) bool {
+		return encoder.Encode(event) == nil
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Errorf("Watch failed: %v", err)
+		os.Exit(1)
+	}
+}
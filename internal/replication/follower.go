@@ -0,0 +1,196 @@
+package replication
+
+import (
+	"context"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// reconnectDelay is how long a follower waits before reconnecting to its leader.
+const reconnectDelay = time.Second
+
+// FollowerState describes how far a follower has caught up with its leader.
+type FollowerState struct {
+	Leader    string `json:"leader"`    // Address of the leader
+	Connected bool   `json:"connected"` // Whether the follower is currently connected
+	Applied   uint64 `json:"applied"`   // Last sequence number applied locally
+	LeaderSeq uint64 `json:"leaderSeq"` // Last sequence number the leader reported
+	Lag       uint64 `json:"lag"`       // Entries the follower has not yet applied
+}
+
+// Follower keeps a local tree in sync with a leader by applying its
+// operation log. The tree should be treated as read-only by everything else.
+type Follower struct {
+	leaderAddr string
+	tree       *tree.Tree
+	log        *logger.Logger
+
+	mu        sync.Mutex
+	epoch     string // Leader run that applied refers to
+	applied   uint64
+	leaderSeq uint64
+	connected bool
+}
+
+// NewFollower creates a Follower that replicates the leader at leaderAddr
+// into t. The tree's contents are replaced by the first snapshot received.
+func NewFollower(leaderAddr string, t *tree.Tree, log *logger.Logger) *Follower {
+	return &Follower{
+		leaderAddr: leaderAddr,
+		tree:       t,
+		log:        log,
+	}
+}
+
+// Tree returns the replicated tree.
+func (f *Follower) Tree() *tree.Tree {
+	return f.tree
+}
+
+// State returns the follower's replication position and lag.
+func (f *Follower) State() FollowerState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state := FollowerState{
+		Leader:    f.leaderAddr,
+		Connected: f.connected,
+		Applied:   f.applied,
+		LeaderSeq: f.leaderSeq,
+	}
+	if f.leaderSeq > f.applied {
+		state.Lag = f.leaderSeq - f.applied
+	}
+	return state
+}
+
+// Lag returns the number of leader entries not yet applied locally.
+func (f *Follower) Lag() uint64 {
+	return f.State().Lag
+}
+
+// Run replicates from the leader until ctx is cancelled, reconnecting after
+// failures. A follower that is new or has fallen too far behind receives a
+// snapshot before streaming resumes.
+func (f *Follower) Run(ctx context.Context) error {
+	for {
+		err := f.sync(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		f.log.Warnf("Replication: lost leader %s: %v; reconnecting", f.leaderAddr, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// sync runs one replication session over a single connection.
+func (f *Follower) sync(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", f.leaderAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	f.mu.Lock()
+	hello := message{Type: msgHello, From: f.applied, Epoch: f.epoch}
+	f.connected = true
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.connected = false
+		f.mu.Unlock()
+	}()
+
+	encoder := json.NewEncoder(conn)
+	if err := encoder.Encode(hello); err != nil {
+		return err
+	}
+	f.log.Infof("Replication: connected to leader %s at seq %d", f.leaderAddr, hello.From)
+
+	// Acknowledge progress periodically so the leader can report lag.
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				f.mu.Lock()
+				ack := message{Type: msgAck, Seq: f.applied}
+				f.mu.Unlock()
+				if err := encoder.Encode(ack); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	decoder := json.NewDecoder(conn)
+	for {
+		var msg message
+		if err := decoder.Decode(&msg); err != nil {
+			return err
+		}
+		if err := f.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle applies one message from the leader.
+func (f *Follower) handle(msg message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch msg.Type {
+	case msgSnapshot:
+		snapshot, err := storage.DecodeTree(msg.Tree)
+		if err != nil {
+			return fmt.Errorf("bad snapshot: %v", err)
+		}
+		f.tree.Restore(snapshot)
+		f.epoch = msg.Epoch
+		f.applied = msg.Seq
+		f.leaderSeq = max(f.leaderSeq, msg.Seq)
+		f.log.Infof("Replication: loaded snapshot at seq %d (%d keys)", msg.Seq, snapshot.Size)
+	case msgEntry:
+		entry := msg.Entry
+		if entry == nil || entry.Seq != f.applied+1 {
+			return fmt.Errorf("out-of-order entry after seq %d", f.applied)
+		}
+		switch entry.Op {
+		case "put":
+			f.tree.Put(entry.Key, entry.Value)
+		case "delete":
+			f.tree.Remove(entry.Key)
+		default:
+			return fmt.Errorf("unknown op %q at seq %d", entry.Op, entry.Seq)
+		}
+		f.applied = entry.Seq
+		f.leaderSeq = max(f.leaderSeq, entry.Seq)
+	case msgHeartbeat:
+		f.leaderSeq = msg.Seq
+	}
+	return nil
+}
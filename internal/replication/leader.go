@@ -0,0 +1,322 @@
+package replication
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultRetain is the number of log entries a leader keeps for followers
+// that reconnect. Followers further behind are sent a snapshot instead.
+const DefaultRetain = 10000
+
+// FollowerStatus describes a connected follower as seen by the leader.
+type FollowerStatus struct {
+	Addr  string `json:"addr"`  // Remote address of the follower
+	Acked uint64 `json:"acked"` // Last sequence number the follower applied
+	Lag   uint64 `json:"lag"`   // Entries the follower has not yet applied
+}
+
+// Leader records every change to a tree in an operation log that it streams
+// to followers over TCP. Changes are taken from the tree's change hook, so
+// writes made directly to the tree, evictions and TTL expiry are replicated as
+// well as writes made through the Leader.
+type Leader struct {
+	tree   *tree.Tree
+	log    *logger.Logger
+	epoch  string
+	retain int
+
+	mu        sync.Mutex
+	seq       uint64        // Sequence number of the last entry
+	entries   []Entry       // Retained tail of the log, ending at seq
+	changed   chan struct{} // Closed and replaced whenever an entry is appended
+	followers map[net.Conn]*FollowerStatus
+}
+
+// NewLeader creates a Leader for the tree, retaining up to retain log entries
+// (DefaultRetain if retain <= 0). The tree's current contents form the
+// snapshot at sequence 0.
+func NewLeader(t *tree.Tree, log *logger.Logger, retain int) *Leader {
+	if retain <= 0 {
+		retain = DefaultRetain
+	}
+	epoch := make([]byte, 8)
+	rand.Read(epoch)
+
+	l := &Leader{
+		tree:      t,
+		log:       log,
+		epoch:     hex.EncodeToString(epoch),
+		retain:    retain,
+		changed:   make(chan struct{}),
+		followers: make(map[net.Conn]*FollowerStatus),
+	}
+	t.OnChange(l.record)
+	return l
+}
+
+// Put inserts or replaces a key; the change is logged by the tree's change hook.
+func (l *Leader) Put(key int, value interface{}) (interface{}, bool) {
+	return l.tree.Put(key, value)
+}
+
+// PutChecked is Put also returning the error that rejected the key, if any
+// (see tree.PutChecked). Rejected keys are not logged.
+func (l *Leader) PutChecked(key int, value interface{}) (interface{}, bool, error) {
+	return l.tree.PutChecked(key, value)
+}
+
+// PutIf is Put applied only when the key's presence matches exists (see
+// tree.PutIf).
+func (l *Leader) PutIf(key int, value interface{}, exists bool) (interface{}, bool, error) {
+	return l.tree.PutIf(key, value, exists)
+}
+
+// InsertWithTTLChecked stores a key that expires after ttl (see
+// tree.InsertWithTTLChecked). Only the write is logged; followers drop the key
+// when the leader's expiry delete reaches them.
+func (l *Leader) InsertWithTTLChecked(key int, value interface{}, ttl time.Duration) (interface{}, bool, error) {
+	return l.tree.InsertWithTTLChecked(key, value, ttl)
+}
+
+// Remove deletes a key; the change is logged by the tree's change hook.
+func (l *Leader) Remove(key int) (interface{}, bool) {
+	return l.tree.Remove(key)
+}
+
+// Seq returns the sequence number of the last logged entry.
+func (l *Leader) Seq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+// Followers returns the status of every connected follower.
+func (l *Leader) Followers() []FollowerStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	statuses := make([]FollowerStatus, 0, len(l.followers))
+	for _, status := range l.followers {
+		s := *status
+		s.Lag = l.seq - s.Acked
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// record logs a change made to the tree. It runs as the tree's change hook,
+// under the tree's write lock, so entries are logged in the order the changes
+// were made.
+func (l *Leader) record(event tree.ChangeEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch event.Op {
+	case tree.OpInsert, tree.OpUpdate:
+		l.append(Entry{Op: "put", Key: event.Key, Value: event.NewValue})
+	case tree.OpDelete:
+		l.append(Entry{Op: "delete", Key: event.Key})
+	case tree.OpReset:
+		// A replaced tree cannot be replayed entry by entry; dropping the log
+		// sends every follower a new snapshot.
+		l.seq++
+		l.entries = nil
+		l.wake()
+	}
+}
+
+// append adds an entry to the log and wakes streaming followers. The caller
+// must hold l.mu.
+func (l *Leader) append(entry Entry) {
+	l.seq++
+	entry.Seq = l.seq
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > l.retain {
+		// Copy instead of reslicing so trimmed entries can be collected.
+		l.entries = append([]Entry(nil), l.entries[len(l.entries)-l.retain:]...)
+	}
+	l.wake()
+}
+
+// wake signals streaming followers that the log changed. The caller must
+// hold l.mu.
+func (l *Leader) wake() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// snapshot encodes the tree with the sequence number of the last change it
+// contains. Changes are logged under the tree's write lock, so holding the
+// read lock keeps the two consistent; the tree lock is taken before l.mu, as
+// by the change hook.
+func (l *Leader) snapshot() ([]byte, uint64, error) {
+	l.tree.Lock.RLock()
+	defer l.tree.Lock.RUnlock()
+
+	l.mu.Lock()
+	seq := l.seq
+	l.mu.Unlock()
+	data, err := json.Marshal(l.tree)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to serialize tree: %v", err)
+	}
+	return data, seq, nil
+}
+
+// ListenAndServe accepts follower connections on addr until ctx is cancelled.
+func (l *Leader) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	return l.Serve(ctx, listener)
+}
+
+// Serve accepts follower connections on an existing listener until ctx is
+// cancelled, then closes all follower connections.
+func (l *Leader) Serve(ctx context.Context, listener net.Listener) error {
+	l.log.Infof("Replication leader listening on %s", listener.Addr())
+
+	var wg sync.WaitGroup
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				wg.Wait()
+				return nil
+			}
+			return fmt.Errorf("accept failed: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.serveFollower(ctx, conn)
+		}()
+	}
+}
+
+// serveFollower handles one follower: it reads the hello, sends a snapshot
+// whenever the follower cannot continue from the retained log, then streams
+// entries and heartbeats until the connection fails or ctx is cancelled.
+func (l *Leader) serveFollower(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	decoder := json.NewDecoder(conn)
+	writer := bufio.NewWriter(conn)
+	encoder := json.NewEncoder(writer)
+
+	var hello message
+	if err := decoder.Decode(&hello); err != nil || hello.Type != msgHello {
+		l.log.Warnf("Replication: bad hello from %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	status := &FollowerStatus{Addr: conn.RemoteAddr().String(), Acked: hello.From}
+	l.mu.Lock()
+	l.followers[conn] = status
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.followers, conn)
+		l.mu.Unlock()
+		l.log.Infof("Replication: follower %s disconnected", status.Addr)
+	}()
+	l.log.Infof("Replication: follower %s connected at seq %d", status.Addr, hello.From)
+
+	// Acknowledgements arrive independently of the entries being sent.
+	go func() {
+		defer cancel()
+		for {
+			var ack message
+			if err := decoder.Decode(&ack); err != nil {
+				return
+			}
+			if ack.Type == msgAck {
+				l.mu.Lock()
+				status.Acked = ack.Seq
+				l.mu.Unlock()
+			}
+		}
+	}()
+
+	// A follower can only resume from a sequence number of this leader run.
+	cursor := hello.From
+	synced := hello.Epoch == l.epoch && hello.From > 0
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		l.mu.Lock()
+		first := l.seq - uint64(len(l.entries)) + 1
+		if !synced || cursor > l.seq || cursor+1 < first {
+			l.mu.Unlock()
+			data, seq, err := l.snapshot()
+			if err != nil {
+				l.log.Errorf("Replication: snapshot for %s failed: %v", status.Addr, err)
+				return
+			}
+			if err := send(writer, encoder, message{Type: msgSnapshot, Epoch: l.epoch, Seq: seq, Tree: data}); err != nil {
+				return
+			}
+			l.log.Infof("Replication: sent snapshot at seq %d to %s", seq, status.Addr)
+			cursor, synced = seq, true
+			continue
+		}
+		pending := l.entries[cursor+1-first:]
+		changed := l.changed
+		seq := l.seq
+		l.mu.Unlock()
+
+		if len(pending) > 0 {
+			for i := range pending {
+				if err := encoder.Encode(message{Type: msgEntry, Entry: &pending[i]}); err != nil {
+					return
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				return
+			}
+			cursor = pending[len(pending)-1].Seq
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-heartbeat.C:
+			if err := send(writer, encoder, message{Type: msgHeartbeat, Seq: seq}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes one message and flushes it.
+func send(writer *bufio.Writer, encoder *json.Encoder, msg message) error {
+	if err := encoder.Encode(msg); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,39 @@
+package replication
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// heartbeatInterval is how often the leader reports its sequence number to
+// idle followers and followers acknowledge what they have applied.
+const heartbeatInterval = 500 * time.Millisecond
+
+// Entry is one mutation in the operation log. Seq numbers start at 1 and
+// increase by one per entry.
+type Entry struct {
+	Seq   uint64      `json:"seq"`
+	Op    string      `json:"op"` // "put" or "delete"
+	Key   int         `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Message types exchanged over a replication connection. Messages are
+// newline-delimited JSON objects.
+const (
+	msgHello     = "hello"     // follower -> leader: resume after From
+	msgAck       = "ack"       // follower -> leader: applied up to Seq
+	msgSnapshot  = "snapshot"  // leader -> follower: full tree as of Seq
+	msgEntry     = "entry"     // leader -> follower: one log entry
+	msgHeartbeat = "heartbeat" // leader -> follower: leader is at Seq
+)
+
+// message is the envelope for every replication message.
+type message struct {
+	Type  string          `json:"type"`
+	From  uint64          `json:"from,omitempty"`  // hello: last sequence already applied
+	Epoch string          `json:"epoch,omitempty"` // hello, snapshot: identifies the leader run the sequence belongs to
+	Seq   uint64          `json:"seq,omitempty"`   // ack, snapshot, heartbeat
+	Tree  json.RawMessage `json:"tree,omitempty"`  // snapshot: tree in storage format
+	Entry *Entry          `json:"entry,omitempty"` // entry
+}
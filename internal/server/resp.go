@@ -0,0 +1,509 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxBulkLength  = 512 * 1024 * 1024 // Bounds a single RESP bulk string accepted from clients
+	maxArrayLength = 1024 * 1024       // Bounds the number of arguments in a RESP command
+)
+
+// ListenAndServeRESP serves a Redis-compatible (RESP2) protocol on addr until
+// ctx is cancelled, then closes client connections and saves the tree.
+//
+// Supported commands are GET, SET (with NX/XX or EX/PX), DEL, EXISTS, DBSIZE,
+// ZRANGEBYSCORE and ZRANK, plus PING, ECHO, SELECT, COMMAND and QUIT for
+// client compatibility. Keys must be integers. The sorted-set commands treat the whole tree as one
+// sorted set whose members are the keys and whose scores equal the keys; the
+// set name argument is accepted but ignored.
+func (s *Server) ListenAndServeRESP(ctx context.Context, addr string, checkpoint time.Duration) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	return s.ServeRESP(ctx, listener, checkpoint)
+}
+
+// ServeRESP is like ListenAndServeRESP but accepts connections on an existing listener.
+func (s *Server) ServeRESP(ctx context.Context, listener net.Listener, checkpoint time.Duration) error {
+	var mu sync.Mutex
+	conns := make(map[net.Conn]struct{})
+	var wg sync.WaitGroup
+	closing := make(chan struct{})
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				select {
+				case <-closing:
+				default:
+					errCh <- err
+				}
+				return
+			}
+			mu.Lock()
+			conns[conn] = struct{}{}
+			mu.Unlock()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.serveRESPConn(conn)
+				mu.Lock()
+				delete(conns, conn)
+				mu.Unlock()
+			}()
+		}
+	}()
+	s.log.Infof("RESP server listening on %s", listener.Addr())
+
+	return s.run(ctx, errCh, checkpoint, func() {
+		s.log.Infof("Shutting down RESP server")
+		close(closing)
+		listener.Close()
+		mu.Lock()
+		for conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	})
+}
+
+// serveRESPConn reads and executes commands from one client until it
+// disconnects or sends QUIT.
+func (s *Server) serveRESPConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		args, err := readRESPCommand(r)
+		var protoErr respProtocolError
+		if errors.As(err, &protoErr) {
+			// The stream can't be resynchronised; report why and hang up.
+			writeRESPError(w, "ERR Protocol error: "+string(protoErr))
+			w.Flush()
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.log.Debugf("RESP: closing connection from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.execRESP(w, args)
+		if err := w.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+// execRESP runs a single command and writes its reply. It reports whether
+// the connection should be closed.
+func (s *Server) execRESP(w *bufio.Writer, args []string) bool {
+	name := strings.ToUpper(args[0])
+	args = args[1:]
+	if s.readOnly && (name == "SET" || name == "DEL") {
+		writeRESPError(w, "READONLY You can't write against a read only replica.")
+		return false
+	}
+
+	switch name {
+	case "PING":
+		if len(args) > 0 {
+			writeRESPBulk(w, args[0])
+		} else {
+			writeRESPSimple(w, "PONG")
+		}
+	case "ECHO":
+		if len(args) != 1 {
+			writeRESPArity(w, name)
+			return false
+		}
+		writeRESPBulk(w, args[0])
+	case "QUIT":
+		writeRESPSimple(w, "OK")
+		return true
+	case "SELECT":
+		if len(args) != 1 || args[0] != "0" {
+			writeRESPError(w, "ERR only database 0 is available")
+			return false
+		}
+		writeRESPSimple(w, "OK")
+	case "COMMAND":
+		// redis-cli asks for command docs on startup; an empty reply is accepted.
+		fmt.Fprint(w, "*0\r\n")
+	case "GET":
+		if len(args) != 1 {
+			writeRESPArity(w, name)
+			return false
+		}
+		key, ok := parseRESPKey(w, args[0])
+		if !ok {
+			return false
+		}
+		if value, found := s.tree.Search(key); found {
+			writeRESPBulk(w, respValueString(value))
+		} else {
+			writeRESPNull(w)
+		}
+	case "SET":
+		s.execSet(w, args)
+	case "DEL", "EXISTS":
+		if len(args) == 0 {
+			writeRESPArity(w, name)
+			return false
+		}
+		keys := make([]int, len(args))
+		for i, arg := range args {
+			key, ok := parseRESPKey(w, arg)
+			if !ok {
+				return false
+			}
+			keys[i] = key
+		}
+		count := 0
+		for _, key := range keys {
+			var found bool
+			if name == "DEL" {
+				_, found = s.writer.Remove(key)
+			} else {
+				_, found = s.tree.Search(key)
+			}
+			if found {
+				count++
+			}
+		}
+		writeRESPInt(w, count)
+	case "DBSIZE":
+		s.tree.Lock.RLock()
+		size := s.tree.Size
+		s.tree.Lock.RUnlock()
+		writeRESPInt(w, size)
+	case "ZRANGEBYSCORE":
+		s.execZRangeByScore(w, args)
+	case "ZRANK":
+		if len(args) != 2 {
+			writeRESPArity(w, name)
+			return false
+		}
+		key, ok := parseRESPKey(w, args[1])
+		if !ok {
+			return false
+		}
+		if rank, found := s.tree.Rank(key); found {
+			writeRESPInt(w, rank)
+		} else {
+			writeRESPNull(w)
+		}
+	default:
+		writeRESPError(w, fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+	}
+	return false
+}
+
+// execSet handles SET key value [NX|XX] [EX seconds|PX milliseconds]. An
+// expiry cannot be combined with NX or XX.
+func (s *Server) execSet(w *bufio.Writer, args []string) {
+	if len(args) < 2 {
+		writeRESPArity(w, "SET")
+		return
+	}
+	key, ok := parseRESPKey(w, args[0])
+	if !ok {
+		return
+	}
+
+	mode := ""
+	var ttl time.Duration
+	for i := 2; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "NX", "XX":
+			if mode != "" {
+				writeRESPError(w, "ERR syntax error")
+				return
+			}
+			mode = option
+		case "EX", "PX":
+			if ttl != 0 || i+1 >= len(args) {
+				writeRESPError(w, "ERR syntax error")
+				return
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || n <= 0 || n > math.MaxInt64/int64(time.Second) {
+				writeRESPError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl = time.Duration(n) * time.Millisecond
+			if option == "EX" {
+				ttl = time.Duration(n) * time.Second
+			}
+		default:
+			writeRESPError(w, "ERR syntax error")
+			return
+		}
+	}
+	if mode != "" && ttl > 0 {
+		writeRESPError(w, "ERR NX and XX cannot be combined with EX or PX")
+		return
+	}
+
+	var stored bool
+	var err error
+	switch {
+	case ttl > 0:
+		_, _, err = s.writer.InsertWithTTLChecked(key, args[1], ttl)
+		stored = err == nil
+	case mode == "":
+		_, _, err = s.writer.PutChecked(key, args[1])
+		stored = err == nil
+	default:
+		_, stored, err = s.writer.PutIf(key, args[1], mode == "XX")
+	}
+	if err != nil {
+		writeRESPError(w, "ERR "+err.Error())
+		return
+	}
+	if !stored {
+		writeRESPNull(w)
+		return
+	}
+	writeRESPSimple(w, "OK")
+}
+
+// execZRangeByScore handles ZRANGEBYSCORE set min max [WITHSCORES] [LIMIT offset count].
+func (s *Server) execZRangeByScore(w *bufio.Writer, args []string) {
+	if len(args) < 3 {
+		writeRESPArity(w, "ZRANGEBYSCORE")
+		return
+	}
+	from, fromEmpty, err1 := parseRESPBound(args[1], true)
+	to, toEmpty, err2 := parseRESPBound(args[2], false)
+	if err1 != nil || err2 != nil {
+		writeRESPError(w, "ERR min or max is not a float")
+		return
+	}
+
+	withScores := false
+	offset, count := 0, -1
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "WITHSCORES":
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				writeRESPError(w, "ERR syntax error")
+				return
+			}
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(args[i+1])
+			count, err2 = strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				writeRESPError(w, "ERR value is not an integer or out of range")
+				return
+			}
+			i += 2
+		default:
+			writeRESPError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	var keys []int
+	if !fromEmpty && !toEmpty && offset >= 0 {
+		skipped := 0
+		s.ascendBetween(from, to, func(key int, _ interface{}) bool {
+			if skipped < offset {
+				skipped++
+				return true
+			}
+			if count >= 0 && len(keys) >= count {
+				return false
+			}
+			keys = append(keys, key)
+			return true
+		})
+	}
+
+	n := len(keys)
+	if withScores {
+		n *= 2
+	}
+	fmt.Fprintf(w, "*%d\r\n", n)
+	for _, key := range keys {
+		writeRESPBulk(w, strconv.Itoa(key))
+		if withScores {
+			writeRESPBulk(w, strconv.Itoa(key))
+		}
+	}
+}
+
+// parseRESPBound parses a ZRANGEBYSCORE bound: an integer, "-inf", "+inf" or
+// "(" followed by an integer for an exclusive bound. Infinite bounds are
+// returned as nil. It reports empty when the bound excludes every key, such
+// as "(" applied to the largest int.
+func parseRESPBound(s string, isMin bool) (*int, bool, error) {
+	switch strings.ToLower(s) {
+	case "-inf":
+		return nil, !isMin, nil
+	case "+inf", "inf":
+		return nil, isMin, nil
+	}
+
+	exclusive := strings.HasPrefix(s, "(")
+	v, err := strconv.Atoi(strings.TrimPrefix(s, "("))
+	if err != nil {
+		return nil, false, err
+	}
+	if exclusive {
+		if isMin {
+			if v == math.MaxInt {
+				return nil, true, nil
+			}
+			v++
+		} else {
+			if v == math.MinInt {
+				return nil, true, nil
+			}
+			v--
+		}
+	}
+	return &v, false, nil
+}
+
+// parseRESPKey converts a key argument, writing an error reply if it is not an integer.
+func parseRESPKey(w *bufio.Writer, arg string) (int, bool) {
+	key, err := strconv.Atoi(arg)
+	if err != nil {
+		writeRESPError(w, "ERR key is not an integer or out of range")
+		return 0, false
+	}
+	return key, true
+}
+
+// respValueString renders a stored value as a bulk string. Strings are sent
+// as-is; other values are JSON-encoded.
+func respValueString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// respProtocolError is returned by readRESPCommand for malformed input, as
+// opposed to a failed read.
+type respProtocolError string
+
+func (e respProtocolError) Error() string {
+	return string(e)
+}
+
+// readRESPCommand reads one command, either as a RESP array of bulk strings
+// or as an inline space-separated line.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > maxArrayLength {
+		return nil, respProtocolError(fmt.Sprintf("invalid array length: %q", line))
+	}
+	// Grow args as elements arrive rather than trusting the declared length.
+	var args []string
+	for i := 0; i < n; i++ {
+		header, err := readRESPLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, respProtocolError(fmt.Sprintf("expected bulk string, got %q", header))
+		}
+		length, err := strconv.Atoi(header[1:])
+		if err != nil || length < 0 || length > maxBulkLength {
+			return nil, respProtocolError(fmt.Sprintf("invalid bulk length: %q", header))
+		}
+		arg, err := readRESPBulk(r, length)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// readRESPBulk reads a bulk string body of the given length and its line
+// terminator. The buffer grows as data arrives, so a large declared length
+// costs nothing until the bytes are actually sent.
+func readRESPBulk(r *bufio.Reader, length int) (string, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(length)); err != nil {
+		return "", err
+	}
+	var crlf [2]byte
+	if _, err := io.ReadFull(r, crlf[:]); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// readRESPLine reads a CRLF- or LF-terminated line without its terminator.
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeRESPSimple(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func writeRESPError(w *bufio.Writer, msg string) {
+	fmt.Fprintf(w, "-%s\r\n", msg)
+}
+
+func writeRESPArity(w *bufio.Writer, name string) {
+	writeRESPError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+func writeRESPInt(w *bufio.Writer, n int) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func writeRESPBulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeRESPNull(w *bufio.Writer) {
+	fmt.Fprint(w, "$-1\r\n")
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// RESPError is an error reply sent by a RESP server.
+type RESPError string
+
+func (e RESPError) Error() string {
+	return string(e)
+}
+
+// RESPClient is a minimal synchronous RESP2 client, intended for tests and
+// tooling that talk to ServeRESP in-process.
+type RESPClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// DialRESP connects to a RESP server at addr.
+func DialRESP(addr string) (*RESPClient, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &RESPClient{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}, nil
+}
+
+// Do sends a command and returns its reply. Replies decode to string (simple
+// and bulk strings), int64, []interface{}, or nil for null replies. Error
+// replies are returned as RESPError.
+func (c *RESPClient) Do(args ...string) (interface{}, error) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// Close closes the connection.
+func (c *RESPClient) Close() error {
+	return c.conn.Close()
+}
+
+// readReply decodes one reply from the server.
+func (c *RESPClient) readReply() (interface{}, error) {
+	line, err := readRESPLine(c.r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RESPError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length: %q", line)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		return readRESPBulk(c.r, length)
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length: %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		// Grow items as replies arrive rather than trusting the declared length.
+		items := []interface{}{}
+		for i := 0; i < n; i++ {
+			item, err := c.readReply()
+			if err != nil {
+				if _, isReplyErr := err.(RESPError); !isReplyErr {
+					return nil, err
+				}
+				item = err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply: %q", strings.TrimSpace(line))
+	}
+}
@@ -0,0 +1,448 @@
+package server
+
+import (
+	"context"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"elastic-btree/pkg/metrics"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultRangeLimit = 100   // Pairs returned by /range when no limit is given
+	maxRangeLimit     = 10000 // Upper bound on the /range limit parameter
+	shutdownTimeout   = 10 * time.Second
+)
+
+// Writer applies mutations to the served tree. *tree.Tree writes directly; a
+// replication leader also records each change in its operation log.
+type Writer interface {
+	PutChecked(key int, value interface{}) (interface{}, bool, error)
+	PutIf(key int, value interface{}, exists bool) (interface{}, bool, error)
+	InsertWithTTLChecked(key int, value interface{}, ttl time.Duration) (interface{}, bool, error)
+	Remove(key int) (interface{}, bool)
+}
+
+// Server exposes a single in-memory tree over HTTP and persists it through storage.
+type Server struct {
+	tree     *tree.Tree
+	storage  *storage.Storage
+	log      *logger.Logger
+	writer   Writer             // Destination of mutations (default: the tree)
+	readOnly bool               // Reject mutations, e.g. on a replication follower
+	status   func() interface{} // Replication status served at /replication, if set
+	dirty    atomic.Bool        // Set by every tree change, cleared by a successful save
+	metrics  *metrics.Registry  // Tree and storage metrics served at /metrics
+
+	checkpoint        atomic.Int64  // Interval between saves while serving (0: none, -1: not yet set)
+	checkpointChanged chan struct{} // Signalled by SetCheckpoint
+}
+
+// Pair is a key-value pair as sent and received by the HTTP API.
+type Pair struct {
+	Key   int         `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// BatchOp is a single operation inside a POST /batch request. Op is one of
+// "get", "put" or "delete".
+type BatchOp struct {
+	Op    string      `json:"op"`
+	Key   int         `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// BatchResult is the outcome of one BatchOp.
+type BatchResult struct {
+	Op    string      `json:"op"`
+	Key   int         `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+	Found bool        `json:"found"`
+	Error string      `json:"error,omitempty"` // Why a put was rejected
+}
+
+// Stats is the response body of GET /stats.
+type Stats struct {
+	Size         int    `json:"size"`
+	Height       int    `json:"height"`
+	Degree       int    `json:"degree"`
+	Evictions    uint64 `json:"evictions"`
+	EvictedBytes uint64 `json:"evictedBytes"`
+}
+
+// New creates a Server for the given tree and storage.
+func New(t *tree.Tree, s *storage.Storage, log *logger.Logger) *Server {
+	reg := metrics.NewRegistry()
+	t.Instrument(reg)
+	s.Instrument(reg)
+	srv := &Server{
+		tree:    t,
+		storage: s,
+		log:     log,
+		writer:  t,
+		metrics: reg,
+
+		checkpointChanged: make(chan struct{}, 1),
+	}
+	srv.checkpoint.Store(-1)
+	// Evictions and expiry change the tree without a request, so track
+	// changes at the source rather than in the handlers.
+	t.OnChange(func(tree.ChangeEvent) { srv.dirty.Store(true) })
+	return srv
+}
+
+// SetWriter routes mutations through w instead of writing to the tree directly.
+func (s *Server) SetWriter(w Writer) {
+	s.writer = w
+}
+
+// SetReadOnly makes the server reject every mutation.
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.readOnly = readOnly
+}
+
+// SetReplicationStatus serves the result of fn at GET /replication.
+func (s *Server) SetReplicationStatus(fn func() interface{}) {
+	s.status = fn
+}
+
+// SetCheckpoint changes the interval between saves while serving; 0 stops
+// checkpointing. It overrides the interval passed to ListenAndServe, and may
+// be called before or while the server runs.
+func (s *Server) SetCheckpoint(interval time.Duration) {
+	s.checkpoint.Store(int64(interval))
+	select {
+	case s.checkpointChanged <- struct{}{}:
+	default: // A change is already pending
+	}
+}
+
+// Handler returns the HTTP handler serving the REST API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys/{key}", s.handleGet)
+	mux.HandleFunc("PUT /keys/{key}", s.handlePut)
+	mux.HandleFunc("DELETE /keys/{key}", s.handleDelete)
+	mux.HandleFunc("GET /range", s.handleRange)
+	mux.HandleFunc("GET /stats", s.handleStats)
+	mux.HandleFunc("POST /batch", s.handleBatch)
+	mux.HandleFunc("GET /watch", s.handleWatch)
+	mux.Handle("GET /metrics", s.metrics)
+	if s.status != nil {
+		mux.HandleFunc("GET /replication", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, s.status())
+		})
+	}
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled, then shuts
+// down gracefully and saves the tree. A positive checkpoint interval also
+// saves modified trees periodically while serving.
+func (s *Server) ListenAndServe(ctx context.Context, addr string, checkpoint time.Duration) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	return s.Serve(ctx, listener, checkpoint)
+}
+
+// Serve is like ListenAndServe but accepts connections on an existing listener.
+func (s *Server) Serve(ctx context.Context, listener net.Listener, checkpoint time.Duration) error {
+	// Long-lived watch streams end when the base context is cancelled, so
+	// they do not hold up a graceful shutdown.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	httpServer := &http.Server{
+		Handler:     s.Handler(),
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.Serve(listener)
+	}()
+	s.log.Infof("HTTP server listening on %s", listener.Addr())
+
+	return s.run(ctx, errCh, checkpoint, func() {
+		s.log.Infof("Shutting down HTTP server")
+		cancelBase()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			s.log.Errorf("Shutdown did not complete: %v", err)
+		}
+	})
+}
+
+// run waits for ctx to be cancelled or the serving goroutine to fail,
+// checkpointing in between. On cancellation it calls shutdown and saves.
+func (s *Server) run(ctx context.Context, errCh <-chan error, checkpoint time.Duration, shutdown func()) error {
+	s.checkpoint.CompareAndSwap(-1, int64(checkpoint))
+	var ticker *time.Ticker
+	var ticks <-chan time.Time
+	resetTicker := func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, ticks = nil, nil
+		}
+		if interval := time.Duration(s.checkpoint.Load()); interval > 0 {
+			ticker = time.NewTicker(interval)
+			ticks = ticker.C
+		}
+	}
+	resetTicker()
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case err := <-errCh:
+			return fmt.Errorf("server failed: %v", err)
+		case <-s.checkpointChanged:
+			resetTicker()
+			s.log.Infof("Checkpoint interval set to %v", time.Duration(s.checkpoint.Load()))
+		case <-ticks:
+			if err := s.Save(); err != nil {
+				s.log.Errorf("Checkpoint failed: %v", err)
+			}
+		case <-ctx.Done():
+			shutdown()
+			return s.Save()
+		}
+	}
+}
+
+// Save writes the tree to storage if it changed since the last save.
+func (s *Server) Save() error {
+	if !s.dirty.Swap(false) {
+		return nil
+	}
+	if err := s.storage.SaveTree(s.tree); err != nil {
+		s.dirty.Store(true)
+		return err
+	}
+	s.log.Infof("Tree saved successfully")
+	return nil
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	key, ok := parseKeyParam(w, r)
+	if !ok {
+		return
+	}
+	value, found := s.tree.Search(key)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("key %d not found", key))
+		return
+	}
+	writeJSON(w, http.StatusOK, Pair{Key: key, Value: value})
+}
+
+func (s *Server) handlePut(w http.ResponseWriter, r *http.Request) {
+	if s.rejectWrite(w) {
+		return
+	}
+	key, ok := parseKeyParam(w, r)
+	if !ok {
+		return
+	}
+	var ttl time.Duration
+	if param := r.URL.Query().Get("ttl"); param != "" {
+		var err error
+		if ttl, err = time.ParseDuration(param); err != nil || ttl <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid ttl: %q", param))
+			return
+		}
+	}
+	var body struct {
+		Value interface{} `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %v", err))
+		return
+	}
+
+	var replaced bool
+	var err error
+	if ttl > 0 {
+		_, replaced, err = s.writer.InsertWithTTLChecked(key, body.Value, ttl)
+	} else {
+		_, replaced, err = s.writer.PutChecked(key, body.Value)
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("key %d rejected: %v", key, err))
+		return
+	}
+	status := http.StatusCreated
+	if replaced {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, Pair{Key: key, Value: body.Value})
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if s.rejectWrite(w) {
+		return
+	}
+	key, ok := parseKeyParam(w, r)
+	if !ok {
+		return
+	}
+	if _, found := s.writer.Remove(key); !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("key %d not found", key))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRange returns pairs with from <= key <= to in key order. Missing
+// bounds are open-ended.
+func (s *Server) handleRange(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var from, to *int
+	limit := defaultRangeLimit
+
+	for _, bound := range []struct {
+		name string
+		dst  **int
+	}{{"from", &from}, {"to", &to}} {
+		if v := query.Get(bound.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: %s", bound.name, v))
+				return
+			}
+			*bound.dst = &n
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxRangeLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %s (must be 1-%d)", v, maxRangeLimit))
+			return
+		}
+	}
+
+	pairs := []Pair{}
+	s.ascendBetween(from, to, func(key int, value interface{}) bool {
+		pairs = append(pairs, Pair{Key: key, Value: value})
+		return len(pairs) < limit
+	})
+	writeJSON(w, http.StatusOK, pairs)
+}
+
+// ascendBetween visits pairs between optional inclusive bounds. A nil bound
+// is replaced by the tree's smallest or largest key rather than a sentinel
+// such as math.MinInt, which the comparator may not order correctly.
+func (s *Server) ascendBetween(from, to *int, fn func(key int, value interface{}) bool) {
+	if from == nil {
+		min, _, ok := s.tree.Min()
+		if !ok {
+			return
+		}
+		from = &min
+	}
+	if to == nil {
+		max, _, ok := s.tree.Max()
+		if !ok {
+			return
+		}
+		to = &max
+	}
+	s.tree.AscendRange(*from, *to, fn)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	s.tree.Lock.RLock()
+	stats := Stats{
+		Size:         s.tree.Size,
+		Height:       s.tree.Height,
+		Degree:       s.tree.Degree,
+		Evictions:    s.tree.Evictions,
+		EvictedBytes: s.tree.EvictedBytes,
+	}
+	s.tree.Lock.RUnlock()
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleBatch applies a list of operations in order. The request is rejected
+// as a whole if any operation is malformed; once applied, operations are not
+// rolled back.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var ops []BatchOp
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %v", err))
+		return
+	}
+	for i, op := range ops {
+		if op.Op != "get" && op.Op != "put" && op.Op != "delete" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("operation %d: unknown op %q", i, op.Op))
+			return
+		}
+		if op.Op != "get" && s.rejectWrite(w) {
+			return
+		}
+	}
+
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		result := BatchResult{Op: op.Op, Key: op.Key}
+		switch op.Op {
+		case "get":
+			result.Value, result.Found = s.tree.Search(op.Key)
+		case "put":
+			var err error
+			_, result.Found, err = s.writer.PutChecked(op.Key, op.Value)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Value = op.Value
+			}
+		case "delete":
+			result.Value, result.Found = s.writer.Remove(op.Key)
+		}
+		results[i] = result
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// rejectWrite writes a 403 response and returns true if the server is read-only.
+func (s *Server) rejectWrite(w http.ResponseWriter) bool {
+	if s.readOnly {
+		writeError(w, http.StatusForbidden, "read-only replica")
+	}
+	return s.readOnly
+}
+
+// parseKeyParam reads the {key} path parameter, writing a 400 response if it
+// is not an integer.
+func parseKeyParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.PathValue("key")
+	key, err := strconv.Atoi(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid key: %s", raw))
+		return 0, false
+	}
+	return key, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"elastic-btree/internal/tree"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// sseKeepAlive is how often an idle Server-Sent Events stream sends a comment
+// so proxies do not close it.
+const sseKeepAlive = 15 * time.Second
+
+// handleWatch streams change events for keys in [from, to] until the client
+// disconnects. The format parameter selects "ndjson" (default) or "sse";
+// clients sending "Accept: text/event-stream" get SSE as well.
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := 0, 0
+	hasFrom, hasTo := false, false
+	var err error
+	if v := query.Get("from"); v != "" {
+		if from, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid from: %s", v))
+			return
+		}
+		hasFrom = true
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid to: %s", v))
+			return
+		}
+		hasTo = true
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "ndjson"
+		if r.Header.Get("Accept") == "text/event-stream" {
+			format = "sse"
+		}
+	}
+	if format != "ndjson" && format != "sse" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid format: %s (must be ndjson or sse)", format))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	// Open bounds watch every key and filter here; unlike a range scan they
+	// cannot be resolved to the current minimum and maximum keys.
+	var watcher *tree.Watcher
+	if hasFrom && hasTo {
+		watcher = s.tree.Watch(from, to, 0)
+	} else {
+		watcher = s.tree.WatchAll(0)
+	}
+	defer watcher.Close()
+
+	if format == "sse" {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if format == "sse" {
+				fmt.Fprint(w, ": keep-alive\n\n")
+				flusher.Flush()
+			}
+		case event, ok := <-watcher.C:
+			if !ok {
+				if err := watcher.Err(); err != nil {
+					writeWatchEvent(w, format, "error", map[string]string{"error": err.Error()})
+					flusher.Flush()
+				}
+				return
+			}
+			if event.Op != tree.OpReset && ((hasFrom && s.tree.Comparator(event.Key, from) < 0) || (hasTo && s.tree.Comparator(event.Key, to) > 0)) {
+				continue
+			}
+			writeWatchEvent(w, format, "change", event)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeWatchEvent writes one event as an NDJSON line or an SSE message.
+func writeWatchEvent(w http.ResponseWriter, format, name string, body interface{}) {
+	data, _ := json.Marshal(body)
+	if format == "sse" {
+		if event, ok := body.(tree.ChangeEvent); ok {
+			fmt.Fprintf(w, "id: %d\n", event.Version)
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+		return
+	}
+	w.Write(append(data, '\n'))
+}
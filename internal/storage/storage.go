@@ -2,44 +2,87 @@ package storage
 
 import (
 	"elastic-btree/internal/tree" // Import the tree package
+	"elastic-btree/pkg/logger"
+	"elastic-btree/pkg/metrics"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 	//"sync"
 )
 
 // Storage represents the persistent storage layer for the B-tree.
 type Storage struct {
 	filePath string // Path to the file where the tree is stored
+	sync     bool   // Flush saves to disk before reporting success
+
+	saveLatency *metrics.Histogram // Duration of successful saves, including encoding
+	loadLatency *metrics.Histogram // Duration of successful loads, including decoding
+	savedBytes  metrics.Counter    // Bytes written by successful saves
+	loadedBytes metrics.Counter    // Bytes read by successful loads
 }
 
 // NewStorage creates a new Storage instance with the given file path.
 func NewStorage(filePath string) *Storage {
 	return &Storage{
-		filePath: filePath,
+		filePath:    filePath,
+		saveLatency: metrics.NewHistogram(nil),
+		loadLatency: metrics.NewHistogram(nil),
 	}
 }
 
+// SetSync sets whether saves are flushed to disk before they report success.
+// Synced saves write a temporary file and rename it over the old one, so a
+// crash leaves either the old or the new tree on disk.
+func (s *Storage) SetSync(sync bool) {
+	s.sync = sync
+}
+
+// Instrument registers save and load durations and byte counts with reg.
+// They are recorded from the start, so loads made before Instrument count.
+func (s *Storage) Instrument(reg *metrics.Registry) {
+	reg.Histogram("elastic_btree_storage_duration_seconds", "Duration of tree saves and loads.", s.saveLatency, "op", "save")
+	reg.Histogram("elastic_btree_storage_duration_seconds", "Duration of tree saves and loads.", s.loadLatency, "op", "load")
+	reg.Counter("elastic_btree_storage_bytes_total", "Bytes written by saves and read by loads.", &s.savedBytes, "op", "save")
+	reg.Counter("elastic_btree_storage_bytes_total", "Bytes written by saves and read by loads.", &s.loadedBytes, "op", "load")
+}
+
 // SaveTree serializes the tree and saves it to disk.
 func (s *Storage) SaveTree(tree *tree.Tree) error {
 	if tree == nil {
 		return errors.New("tree is nil")
 	}
 
-	// Serialize the tree to JSON
-	data, err := json.Marshal(tree)
+	start := time.Now()
+	data, err := EncodeTree(tree)
+	if err == nil {
+		err = s.writeFile(data)
+	}
+	if tree.Tracer != nil {
+		tree.Tracer.Save(s.filePath, len(data), time.Since(start), err)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to serialize tree: %v", err)
+		return err
 	}
+	s.saveLatency.ObserveSince(start)
+	s.savedBytes.Add(uint64(len(data)))
+	return nil
+}
 
+// writeFile writes serialized tree data to the storage path.
+func (s *Storage) writeFile(data []byte) error {
 	// Ensure the directory exists
 	dir := filepath.Dir(s.filePath)
 	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create directory: %v", err)
 	}
 
+	if s.sync {
+		return s.writeFileSync(dir, data)
+	}
+
 	// Write the serialized data to the file
 	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write tree to file: %v", err)
@@ -48,9 +91,60 @@ func (s *Storage) SaveTree(tree *tree.Tree) error {
 	return nil
 }
 
+// writeFileSync writes data to a temporary file, flushes it, and renames it
+// over the storage file.
+func (s *Storage) writeFileSync(dir string, data []byte) error {
+	file, err := os.CreateTemp(dir, filepath.Base(s.filePath)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(file.Name()) // Fails harmlessly after the rename
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write tree to file: %v", err)
+	}
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write tree to file: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync tree file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write tree to file: %v", err)
+	}
+	if err := os.Rename(file.Name(), s.filePath); err != nil {
+		return fmt.Errorf("failed to replace tree file: %v", err)
+	}
+
+	// Flush the rename itself.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
 // LoadTree loads the tree from disk and deserializes it.
 func (s *Storage) LoadTree() (*tree.Tree, error) {
-	// Read the file
+	start := time.Now()
+	data, err := s.readFile()
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := DecodeTree(data)
+	if err != nil {
+		return nil, err
+	}
+	s.loadLatency.ObserveSince(start)
+	s.loadedBytes.Add(uint64(len(data)))
+	return loaded, nil
+}
+
+// readFile reads serialized tree data from the storage path.
+func (s *Storage) readFile() ([]byte, error) {
 	data, err := os.ReadFile(s.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -58,8 +152,52 @@ func (s *Storage) LoadTree() (*tree.Tree, error) {
 		}
 		return nil, fmt.Errorf("failed to read file: %v", err)
 	}
+	return data, nil
+}
 
-	// Deserialize the tree
+// SaveBytesTree serializes a byte-keyed tree and saves it to disk. Nodes are
+// written with their shared prefixes factored out, as they are held in memory.
+func (s *Storage) SaveBytesTree(tree *tree.BytesTree) error {
+	if tree == nil {
+		return errors.New("tree is nil")
+	}
+
+	data, err := EncodeBytesTree(tree)
+	if err != nil {
+		return err
+	}
+	return s.writeFile(data)
+}
+
+// LoadBytesTree loads a byte-keyed tree saved by SaveBytesTree. The tree logs
+// to log.
+func (s *Storage) LoadBytesTree(log *logger.Logger) (*tree.BytesTree, error) {
+	data, err := s.readFile()
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBytesTree(data, log)
+}
+
+// EncodeTree serializes the tree in the storage file format. A tree whose
+// comparator has no registered name is rejected, since it could not be loaded
+// in the same order.
+func EncodeTree(tree *tree.Tree) ([]byte, error) {
+	if err := tree.CheckComparatorName(); err != nil {
+		return nil, fmt.Errorf("failed to serialize tree: %v", err)
+	}
+	// Hold the read lock so concurrent writers cannot modify nodes mid-encode.
+	tree.Lock.RLock()
+	data, err := json.Marshal(tree)
+	tree.Lock.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize tree: %v", err)
+	}
+	return data, nil
+}
+
+// DecodeTree deserializes a tree written by EncodeTree.
+func DecodeTree(data []byte) (*tree.Tree, error) {
 	var tree tree.Tree
 	if err := json.Unmarshal(data, &tree); err != nil {
 		return nil, fmt.Errorf("failed to deserialize tree: %v", err)
@@ -67,12 +205,48 @@ func (s *Storage) LoadTree() (*tree.Tree, error) {
 
 	// Reinitialize fields that can't be serialized
 	//tree.Lock = sync.RWMutex{}
-	tree.Comparator = func(a, b int) int { return a - b } // Default comparator
+	if err := tree.ResolveComparator(); err != nil {
+		return nil, fmt.Errorf("failed to load tree: %w", err)
+	}
+	for name, index := range tree.Indexes {
+		if index.Tree == nil {
+			return nil, fmt.Errorf("failed to load tree: index %q is empty", name)
+		}
+		if err := index.Tree.ResolveComparator(); err != nil {
+			return nil, fmt.Errorf("failed to load index %q: %w", name, err)
+		}
+		index.Tree.RebuildParentPointers()
+	}
 
 	tree.RebuildParentPointers()
 	return &tree, nil
 }
 
+// EncodeBytesTree serializes a byte-keyed tree in the storage file format.
+func EncodeBytesTree(tree *tree.BytesTree) ([]byte, error) {
+	tree.Lock.RLock()
+	data, err := json.Marshal(tree)
+	tree.Lock.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize tree: %v", err)
+	}
+	return data, nil
+}
+
+// DecodeBytesTree deserializes a tree written by EncodeBytesTree. The tree
+// logs to log.
+func DecodeBytesTree(data []byte, log *logger.Logger) (*tree.BytesTree, error) {
+	var tree tree.BytesTree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to deserialize tree: %v", err)
+	}
+	if tree.Degree < 2 {
+		return nil, fmt.Errorf("failed to deserialize tree: invalid degree %d", tree.Degree)
+	}
+	tree.Logger = log
+	return &tree, nil
+}
+
 // DeleteTree deletes the tree's storage file.
 func (s *Storage) DeleteTree() error {
 	if err := os.Remove(s.filePath); err != nil {
@@ -1,72 +1,32 @@
 package tree
 
-// balanceAfterDeletion checks if a node is underfilled and rebalances the tree.
-func (t *Tree) balanceAfterDeletion(node *Node) {
-	// Add nil check for logger
-    if t.Logger == nil {
-        panic("logger not initialized")
-    }
-    if node == nil {
-        return
-    }
-
-    // If node has enough keys or is root, no balancing needed
-    if node.Size >= node.MinKeys || node.Parent == nil {
-        // Handle root becoming empty
-        if node.Parent == nil && node.Size == 0 && len(node.Children) > 0 {
-            t.Root = node.Children[0]
-            t.Root.Parent = nil
-            t.Height--
-        }
-        return
-    }
-
-    parent := node.Parent
-    childIndex := -1
-
-    // Find the index of the node in parent's children
-    for i, child := range parent.Children {
-        if child == node {
-            childIndex = i
-            break
-        }
-    }
-
-    // Validate child index
-    if childIndex < 0 || childIndex >= len(parent.Children) {
-        t.Logger.Panicf("invalid child index %d in parent with %d children",
-            childIndex, len(parent.Children))
-    }
-
-    // Try to borrow from left sibling
-    if childIndex > 0 {
-        leftSibling := parent.Children[childIndex-1]
-        if leftSibling.Size > leftSibling.MinKeys {
-            t.borrowFromLeftSibling(parent, childIndex)
-            return
-        }
-    }
-
-    // Try to borrow from right sibling
-    if childIndex < len(parent.Children)-1 {
-        rightSibling := parent.Children[childIndex+1]
-        if rightSibling.Size > rightSibling.MinKeys {
-            t.borrowFromRightSibling(parent, childIndex)
-            return
-        }
-    }
+import "elastic-btree/pkg/logger"
+
+// fillChild makes sure the child at index has more than MinKeys keys before
+// deletion descends into it, borrowing from a sibling or merging with one. It
+// returns the index of the child that now covers the same keys.
+func (t *Tree) fillChild(parent *Node, index int) int {
+	child := parent.Children[index]
+	if child.Size > child.MinKeys {
+		return index
+	}
 
-    // Merge with a sibling if borrowing failed
-    if childIndex > 0 {
-        t.mergeWithLeftSibling(parent, childIndex-1)
-    } else {
-        t.mergeWithRightSibling(parent, childIndex)
-    }
+	if index > 0 && parent.Children[index-1].Size > parent.Children[index-1].MinKeys {
+		t.borrowFromLeftSibling(parent, index)
+		return index
+	}
+	if index < parent.Size && parent.Children[index+1].Size > parent.Children[index+1].MinKeys {
+		t.borrowFromRightSibling(parent, index)
+		return index
+	}
 
-    // Propagate balancing to parent if necessary
-    if parent.Size < parent.MinKeys {
-        t.balanceAfterDeletion(parent)
-    }
+	// Both siblings are minimal, so merge with one of them.
+	if index < parent.Size {
+		t.mergeWithRightSibling(parent, index)
+		return index
+	}
+	t.mergeWithRightSibling(parent, index-1)
+	return index - 1
 }
 
 // borrowFromLeftSibling borrows a key from the left sibling.
@@ -76,8 +36,11 @@ func (t *Tree) borrowFromLeftSibling(parent *Node, index int) {
     }
     node := parent.Children[index]
     leftSibling := parent.Children[index-1]
+    t.borrows++
 
-    t.Logger.Infof("borrowFromLeftSibling: before borrowing, node keys: %v, leftSibling keys: %v", node.Keys, leftSibling.Keys)
+    if t.Logger.Enabled(logger.Debug) {
+        t.Logger.Debugf("borrowFromLeftSibling: before borrowing, node keys: %v, leftSibling keys: %v", node.Keys, leftSibling.Keys)
+    }
 
     // Move parent's key down to node.
     node.Keys = append([]int{parent.Keys[index-1]}, node.Keys...)
@@ -94,14 +57,20 @@ func (t *Tree) borrowFromLeftSibling(parent *Node, index int) {
     leftSibling.Size--
 
     if !node.IsLeaf {
-        borrowedChild := leftSibling.Children[leftSibling.Size]
+        // The left sibling's last child moves along with its last key.
+        borrowedChild := leftSibling.Children[leftSibling.Size+1]
         node.Children = append([]*Node{borrowedChild}, node.Children...)
         borrowedChild.Parent = node
         leftSibling.Children = leftSibling.Children[:leftSibling.Size+1]
     }
 
-    t.Logger.Infof("borrowFromLeftSibling: after borrowing, node keys: %v, leftSibling keys: %v", node.Keys, leftSibling.Keys)
+    if t.Logger.Enabled(logger.Debug) {
+        t.Logger.Debugf("borrowFromLeftSibling: after borrowing, node keys: %v, leftSibling keys: %v", node.Keys, leftSibling.Keys)
+    }
     t.checkInvariants(parent)
+    if t.Tracer != nil {
+        t.Tracer.Borrow(leftSibling, node)
+    }
 }
 
 
@@ -109,6 +78,7 @@ func (t *Tree) borrowFromLeftSibling(parent *Node, index int) {
 func (t *Tree) borrowFromRightSibling(parent *Node, index int) {
 	node := parent.Children[index]
 	rightSibling := parent.Children[index+1]
+	t.borrows++
 
 	// Move parent's key down to node
 	node.Keys = append(node.Keys, parent.Keys[index])
@@ -134,88 +104,20 @@ func (t *Tree) borrowFromRightSibling(parent *Node, index int) {
 		// Remove child from right sibling
 		rightSibling.Children = rightSibling.Children[1:]
 	}
+	if t.Tracer != nil {
+		t.Tracer.Borrow(rightSibling, node)
+	}
 }
 
-// mergeWithLeftSibling merges the node with its left sibling.
-func (t *Tree) mergeWithLeftSibling(parent *Node, leftIndex int) {
-    if parent == nil {
-        t.Logger.Panicf("mergeWithLeftSibling: parent is nil")
-    }
-    t.Logger.Infof("mergeWithLeftSibling: BEFORE merge, leftIndex: %d, parent keys: %v, children count: %d",
-        leftIndex, parent.Keys, len(parent.Children))
-    originalParentSize := len(parent.Keys)
-    if leftIndex < 0 || leftIndex >= originalParentSize {
-        t.Logger.Panicf("mergeWithLeftSibling: invalid merge index %d (parent has %d keys)", leftIndex, originalParentSize)
-    }
-    if leftIndex+1 >= len(parent.Children) {
-        t.Logger.Panicf("mergeWithLeftSibling: not enough children, leftIndex %d, children length %d", leftIndex, len(parent.Children))
-    }
-
-    leftSibling := parent.Children[leftIndex]
-    node := parent.Children[leftIndex+1]
-
-    t.Logger.Infof("mergeWithLeftSibling: BEFORE merge, leftSibling keys: %v, node keys: %v", leftSibling.Keys, node.Keys)
-
-    // Move the separator key from parent to leftSibling.
-    leftSibling.Keys = append(leftSibling.Keys, parent.Keys[leftIndex])
-    leftSibling.Values = append(leftSibling.Values, parent.Values[leftIndex])
-    leftSibling.Size = len(leftSibling.Keys)
-
-    // Merge node's keys and values into leftSibling.
-    leftSibling.Keys = append(leftSibling.Keys, node.Keys...)
-    leftSibling.Values = append(leftSibling.Values, node.Values...)
-    leftSibling.Size = len(leftSibling.Keys)
-
-    // Merge children if node is not a leaf.
-    if !node.IsLeaf {
-        leftSibling.Children = append(leftSibling.Children, node.Children...)
-        for _, child := range node.Children {
-            child.Parent = leftSibling
-        }
-    }
-
-    // Remove the separator key and the merged child (node) from parent.
-    parent.Keys = append(parent.Keys[:leftIndex], parent.Keys[leftIndex+1:]...)
-    parent.Values = append(parent.Values[:leftIndex], parent.Values[leftIndex+1:]...)
-    // Instead of using append directly on parent.Children, create a new slice.
-    newChildren := make([]*Node, 0, len(parent.Children)-1)
-    newChildren = append(newChildren, parent.Children[:leftIndex+1]...)
-    newChildren = append(newChildren, parent.Children[leftIndex+2:]...)
-    parent.Children = newChildren
-
-    // Ensure leftSibling remains at the correct position.
-    parent.Children[leftIndex] = leftSibling
-    parent.Size = len(parent.Keys)
-
-    // Extra trimming (should be redundant now, but we check):
-    expectedChildrenCount := parent.Size + 1
-    if len(parent.Children) != expectedChildrenCount {
-        t.Logger.Panicf("mergeWithLeftSibling: invariant violation: parent's children count %d != keys count %d + 1",
-            len(parent.Children), parent.Size)
-    }
-
-    t.Logger.Infof("mergeWithLeftSibling: AFTER merge, parent keys: %v, children count: %d",
-        parent.Keys, len(parent.Children))
-    t.checkInvariants(parent)
-
-    // If parent is the root and becomes empty, update the tree’s root.
-    if parent.Parent == nil && parent.Size == 0 {
-        t.Logger.Infof("mergeWithLeftSibling: parent is root and empty, replacing root with leftSibling keys: %v",
-            leftSibling.Keys)
-        t.Root = leftSibling
-        leftSibling.Parent = nil
-        t.Height--
-    }
-}
-
-
 // mergeWithRightSibling merges the node with its right sibling.
 func (t *Tree) mergeWithRightSibling(parent *Node, index int) {
     if parent == nil {
         t.Logger.Panicf("mergeWithRightSibling: parent is nil")
     }
-    t.Logger.Infof("mergeWithRightSibling: BEFORE merge, index: %d, parent keys: %v, children count: %d",
-        index, parent.Keys, len(parent.Children))
+    if t.Logger.Enabled(logger.Debug) {
+        t.Logger.Debugf("mergeWithRightSibling: BEFORE merge, index: %d, parent keys: %v, children count: %d",
+            index, parent.Keys, len(parent.Children))
+    }
     parent.Size = len(parent.Keys)
     if parent.Size == 0 {
         t.Logger.Panicf("mergeWithRightSibling: parent has 0 keys, cannot merge")
@@ -229,9 +131,12 @@ func (t *Tree) mergeWithRightSibling(parent *Node, index int) {
 
     node := parent.Children[index]
     rightSibling := parent.Children[index+1]
+    t.merges++
 
-    t.Logger.Infof("mergeWithRightSibling: BEFORE merge, node keys: %v, rightSibling keys: %v",
-        node.Keys, rightSibling.Keys)
+    if t.Logger.Enabled(logger.Debug) {
+        t.Logger.Debugf("mergeWithRightSibling: BEFORE merge, node keys: %v, rightSibling keys: %v",
+            node.Keys, rightSibling.Keys)
+    }
 
     // Move parent's key down to node.
     node.Keys = append(node.Keys, parent.Keys[index])
@@ -243,6 +148,10 @@ func (t *Tree) mergeWithRightSibling(parent *Node, index int) {
     node.Values = append(node.Values, rightSibling.Values...)
     node.Size = len(node.Keys)
 
+    // Siblings may have different capacities under a sizing policy.
+    node.MaxKeys = max(node.MaxKeys, rightSibling.MaxKeys, node.Size)
+    node.MinKeys = t.minKeysFor(node.MaxKeys)
+
     // Merge children if node is not a leaf.
     if !node.IsLeaf {
         node.Children = append(node.Children, rightSibling.Children...)
@@ -269,8 +178,13 @@ func (t *Tree) mergeWithRightSibling(parent *Node, index int) {
             len(parent.Children), parent.Size)
     }
 
-    t.Logger.Infof("mergeWithRightSibling: AFTER merge, parent keys: %v, children count: %d",
-        parent.Keys, len(parent.Children))
+    if t.Logger.Enabled(logger.Debug) {
+        t.Logger.Debugf("mergeWithRightSibling: AFTER merge, parent keys: %v, children count: %d",
+            parent.Keys, len(parent.Children))
+    }
     t.checkInvariants(parent)
+    if t.Tracer != nil {
+        t.Tracer.Merge(node, rightSibling)
+    }
 }
 
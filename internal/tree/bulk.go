@@ -0,0 +1,123 @@
+package tree
+
+import "fmt"
+
+// BulkLoad builds the tree bottom-up from keys that are already in strictly
+// ascending order. It is much faster than repeated Insert calls but only works
+// on an empty tree.
+func (t *Tree) BulkLoad(keys []int, values []interface{}) error {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	if t.Root != nil && t.Root.Size > 0 {
+		return fmt.Errorf("bulk load requires an empty tree (size %d)", t.Size)
+	}
+	if len(keys) != len(values) {
+		return fmt.Errorf("bulk load got %d keys but %d values", len(keys), len(values))
+	}
+	for i := 1; i < len(keys); i++ {
+		if t.Comparator(keys[i-1], keys[i]) >= 0 {
+			return fmt.Errorf("bulk load keys are not strictly ascending at index %d (%d after %d)", i, keys[i], keys[i-1])
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// Build the leaf level; the keys between leaves become separators.
+	var nodes []*Node
+	var sepKeys []int
+	var sepValues []interface{}
+	idx := 0
+	sizes := t.bulkNodeSizes(len(keys))
+	for n, size := range sizes {
+		nodes = append(nodes, &Node{
+			Keys:     append([]int{}, keys[idx:idx+size]...),
+			Values:   append([]interface{}{}, values[idx:idx+size]...),
+			Children: []*Node{},
+			IsLeaf:   true,
+			Size:     size,
+			MaxKeys:  2*t.Degree - 1,
+			MinKeys:  t.Degree - 1,
+		})
+		idx += size
+		if n < len(sizes)-1 {
+			sepKeys = append(sepKeys, keys[idx])
+			sepValues = append(sepValues, values[idx])
+			idx++
+		}
+	}
+	height := 1
+
+	// Group each level under parents built from its separators until a single
+	// root remains.
+	for len(nodes) > 1 {
+		var parents []*Node
+		var nextKeys []int
+		var nextValues []interface{}
+		keyIdx, childIdx := 0, 0
+		sizes := t.bulkNodeSizes(len(sepKeys))
+		for n, size := range sizes {
+			parent := &Node{
+				Keys:     append([]int{}, sepKeys[keyIdx:keyIdx+size]...),
+				Values:   append([]interface{}{}, sepValues[keyIdx:keyIdx+size]...),
+				Children: append([]*Node{}, nodes[childIdx:childIdx+size+1]...),
+				IsLeaf:   false,
+				Size:     size,
+				MaxKeys:  2*t.Degree - 1,
+				MinKeys:  t.Degree - 1,
+			}
+			for _, child := range parent.Children {
+				child.Parent = parent
+			}
+			parents = append(parents, parent)
+			keyIdx += size
+			childIdx += size + 1
+			if n < len(sizes)-1 {
+				nextKeys = append(nextKeys, sepKeys[keyIdx])
+				nextValues = append(nextValues, sepValues[keyIdx])
+				keyIdx++
+			}
+		}
+		nodes, sepKeys, sepValues = parents, nextKeys, nextValues
+		height++
+	}
+
+	t.Root = nodes[0]
+	t.Root.Parent = nil
+	t.Size = len(keys)
+	t.Height = height
+	t.checkInvariants(t.Root)
+	for i, key := range keys {
+		t.indexAdd(key, values[i])
+		t.notify(OpInsert, key, nil, values[i])
+	}
+	for i, key := range keys {
+		t.track(key, values[i])
+	}
+	t.Logger.Infof("BulkLoad: loaded %d keys, height %d", len(keys), height)
+	return nil
+}
+
+// bulkNodeSizes splits count items into node sizes for one level. Every node
+// but the last is followed by a separator that moves up a level, so the sizes
+// plus separators add up to count. Sizes are spread evenly and always fall
+// within [MinKeys, MaxKeys] when more than one node is needed.
+func (t *Tree) bulkNodeSizes(count int) []int {
+	maxKeys := 2*t.Degree - 1
+	if count <= maxKeys {
+		return []int{count}
+	}
+
+	// Each node consumes its keys plus one separator, at most 2*Degree slots.
+	nodes := (count + 1 + 2*t.Degree - 1) / (2 * t.Degree)
+	keys := count - (nodes - 1)
+	sizes := make([]int, nodes)
+	for i := range sizes {
+		sizes[i] = keys / nodes
+		if i < keys%nodes {
+			sizes[i]++
+		}
+	}
+	return sizes
+}
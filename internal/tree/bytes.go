@@ -0,0 +1,476 @@
+package tree
+
+import (
+	"bytes"
+	"elastic-btree/pkg/logger"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// BytesTree is a B+ tree keyed by byte slices in bytes.Compare (memcmp)
+// order. Values live in the leaves; internal nodes only hold the shortest
+// separators that route between their children (suffix truncation), and every
+// node stores the prefix shared by its keys once (prefix compression). Use
+// pkg/keys to build order-preserving composite keys.
+type BytesTree struct {
+	Root   *BytesNode     `json:"root"`   // Root node of the tree (nil when empty)
+	Degree int            `json:"degree"` // Minimum degree of the tree
+	Size   int            `json:"size"`   // Total number of keys in the tree
+	Height int            `json:"height"` // Height of the tree
+	Lock   sync.RWMutex   `json:"-"`      // Mutex for concurrent access
+	Logger *logger.Logger `json:"-"`      // Custom logger for debugging
+}
+
+// BytesNode is a node of a BytesTree. Key i is Prefix followed by Suffixes[i].
+// In a leaf the keys are the stored keys; in an internal node they are
+// separators: every key in Children[i] sorts before key i, and every key in
+// Children[i+1] sorts at or after it.
+type BytesNode struct {
+	Prefix   []byte        `json:"prefix,omitempty"`   // Bytes shared by every key in the node
+	Suffixes [][]byte      `json:"suffixes"`           // Keys with Prefix removed
+	Values   []interface{} `json:"values,omitempty"`   // Values of a leaf's keys
+	Children []*BytesNode  `json:"children,omitempty"` // Children of an internal node
+	IsLeaf   bool          `json:"isLeaf"`
+}
+
+// NewBytesTree creates an empty BytesTree with the given degree and logger.
+func NewBytesTree(degree int, logger *logger.Logger) *BytesTree {
+	if degree < 2 {
+		logger.Panicf("degree must be at least 2")
+	}
+	return &BytesTree{Degree: degree, Logger: logger}
+}
+
+func (t *BytesTree) maxKeys() int { return 2*t.Degree - 1 }
+func (t *BytesTree) minKeys() int { return t.Degree - 1 }
+
+// Len returns the number of keys in the tree.
+func (t *BytesTree) Len() int {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+	return t.Size
+}
+
+// Get returns the value stored under key.
+func (t *BytesTree) Get(key []byte) (interface{}, bool) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	node := t.Root
+	if node == nil {
+		return nil, false
+	}
+	for !node.IsLeaf {
+		node = node.Children[node.childIndex(key)]
+	}
+	if i, found := node.search(key); found {
+		return node.Values[i], true
+	}
+	return nil, false
+}
+
+// Put inserts a key or replaces its value. It returns the previous value and
+// whether the key was present. The key is copied.
+func (t *BytesTree) Put(key []byte, value interface{}) (interface{}, bool) {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	if t.Root == nil {
+		t.Root = &BytesNode{IsLeaf: true}
+		t.Height = 1
+	}
+	old, replaced, right, sep := t.put(t.Root, key, value)
+	if right != nil {
+		root := &BytesNode{Children: []*BytesNode{t.Root, right}}
+		root.setKeys([][]byte{sep})
+		t.Root = root
+		t.Height++
+		t.Logger.Debugf("BytesTree: root split, height %d", t.Height)
+	}
+	if !replaced {
+		t.Size++
+	}
+	return old, replaced
+}
+
+// put inserts into the subtree rooted at node. If node overflows it is split
+// and the new right sibling is returned with the separator that goes before it.
+func (t *BytesTree) put(node *BytesNode, key []byte, value interface{}) (old interface{}, replaced bool, right *BytesNode, sep []byte) {
+	if node.IsLeaf {
+		i, found := node.search(key)
+		if found {
+			old = node.Values[i]
+			node.Values[i] = value
+			return old, true, nil, nil
+		}
+		node.insertKey(i, key)
+		node.Values = slices.Insert(node.Values, i, value)
+	} else {
+		i := node.childIndex(key)
+		old, replaced, right, sep = t.put(node.Children[i], key, value)
+		if right == nil {
+			return old, replaced, nil, nil
+		}
+		node.insertKey(i, sep)
+		node.Children = slices.Insert(node.Children, i+1, right)
+	}
+
+	if len(node.Suffixes) <= t.maxKeys() {
+		return old, replaced, nil, nil
+	}
+	right, sep = t.split(node)
+	return old, replaced, right, sep
+}
+
+// split moves the upper half of an overfull node into a new right sibling and
+// returns it with the separator for the parent.
+func (t *BytesTree) split(node *BytesNode) (*BytesNode, []byte) {
+	keys := node.keys()
+	mid := len(keys) / 2
+	right := &BytesNode{IsLeaf: node.IsLeaf}
+
+	if node.IsLeaf {
+		right.setKeys(keys[mid:])
+		right.Values = append([]interface{}(nil), node.Values[mid:]...)
+		node.setKeys(keys[:mid])
+		node.Values = node.Values[:mid:mid]
+		return right, separator(keys[mid-1], keys[mid])
+	}
+
+	// The middle separator moves up to the parent.
+	right.setKeys(keys[mid+1:])
+	right.Children = append([]*BytesNode(nil), node.Children[mid+1:]...)
+	node.setKeys(keys[:mid])
+	node.Children = node.Children[: mid+1 : mid+1]
+	return right, keys[mid]
+}
+
+// Delete removes a key. It returns the removed value and whether the key was
+// present.
+func (t *BytesTree) Delete(key []byte) (interface{}, bool) {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	if t.Root == nil {
+		return nil, false
+	}
+	old, found := t.delete(t.Root, key)
+	if !found {
+		return nil, false
+	}
+	t.Size--
+	if !t.Root.IsLeaf && len(t.Root.Suffixes) == 0 {
+		t.Root = t.Root.Children[0]
+		t.Height--
+	} else if t.Root.IsLeaf && len(t.Root.Suffixes) == 0 {
+		t.Root = nil
+		t.Height = 0
+	}
+	return old, true
+}
+
+// delete removes key from the subtree rooted at node, rebalancing children
+// that fall below the minimum on the way back up. Separators are left alone:
+// they still route correctly after the key they were derived from is gone.
+func (t *BytesTree) delete(node *BytesNode, key []byte) (interface{}, bool) {
+	if node.IsLeaf {
+		i, found := node.search(key)
+		if !found {
+			return nil, false
+		}
+		old := node.Values[i]
+		node.deleteKey(i)
+		node.Values = slices.Delete(node.Values, i, i+1)
+		return old, true
+	}
+
+	i := node.childIndex(key)
+	old, found := t.delete(node.Children[i], key)
+	if found && len(node.Children[i].Suffixes) < t.minKeys() {
+		t.rebalance(node, i)
+	}
+	return old, found
+}
+
+// rebalance refills the underfull child at index by borrowing from a sibling
+// or, if both siblings are minimal, merging with one.
+func (t *BytesTree) rebalance(parent *BytesNode, index int) {
+	child := parent.Children[index]
+	seps := parent.keys()
+
+	if index > 0 && len(parent.Children[index-1].Suffixes) > t.minKeys() {
+		left := parent.Children[index-1]
+		leftKeys := left.keys()
+		last := len(leftKeys) - 1
+		if child.IsLeaf {
+			child.setKeys(slices.Insert(child.keys(), 0, leftKeys[last]))
+			child.Values = slices.Insert(child.Values, 0, left.Values[last])
+			left.Values = slices.Delete(left.Values, last, last+1)
+			seps[index-1] = separator(leftKeys[last-1], leftKeys[last])
+		} else {
+			child.setKeys(slices.Insert(child.keys(), 0, seps[index-1]))
+			child.Children = slices.Insert(child.Children, 0, left.Children[last+1])
+			left.Children = slices.Delete(left.Children, last+1, last+2)
+			seps[index-1] = leftKeys[last]
+		}
+		left.setKeys(leftKeys[:last])
+		parent.setKeys(seps)
+		return
+	}
+
+	if index < len(parent.Children)-1 && len(parent.Children[index+1].Suffixes) > t.minKeys() {
+		right := parent.Children[index+1]
+		rightKeys := right.keys()
+		if child.IsLeaf {
+			child.setKeys(append(child.keys(), rightKeys[0]))
+			child.Values = append(child.Values, right.Values[0])
+			right.Values = slices.Delete(right.Values, 0, 1)
+			seps[index] = separator(rightKeys[0], rightKeys[1])
+		} else {
+			child.setKeys(append(child.keys(), seps[index]))
+			child.Children = append(child.Children, right.Children[0])
+			right.Children = slices.Delete(right.Children, 0, 1)
+			seps[index] = rightKeys[0]
+		}
+		right.setKeys(rightKeys[1:])
+		parent.setKeys(seps)
+		return
+	}
+
+	// Merge the child with a sibling; index becomes the left of the pair.
+	if index == len(parent.Children)-1 {
+		index--
+	}
+	left, right := parent.Children[index], parent.Children[index+1]
+	keys := left.keys()
+	if !left.IsLeaf {
+		keys = append(keys, seps[index])
+	}
+	left.setKeys(append(keys, right.keys()...))
+	left.Values = append(left.Values, right.Values...)
+	left.Children = append(left.Children, right.Children...)
+	parent.setKeys(slices.Delete(seps, index, index+1))
+	parent.Children = slices.Delete(parent.Children, index+1, index+2)
+}
+
+// Ascend calls fn for every key in order until fn returns false.
+func (t *BytesTree) Ascend(fn func(key []byte, value interface{}) bool) {
+	t.AscendRange(nil, nil, fn)
+}
+
+// AscendRange calls fn in order for every key with from <= key <= to until fn
+// returns false. A nil bound is open.
+func (t *BytesTree) AscendRange(from, to []byte, fn func(key []byte, value interface{}) bool) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	if t.Root != nil {
+		t.ascend(t.Root, from, to, fn)
+	}
+}
+
+// AscendPrefix calls fn in order for every key starting with prefix until fn
+// returns false.
+func (t *BytesTree) AscendPrefix(prefix []byte, fn func(key []byte, value interface{}) bool) {
+	t.AscendRange(prefix, nil, func(key []byte, value interface{}) bool {
+		return bytes.HasPrefix(key, prefix) && fn(key, value)
+	})
+}
+
+// ascend walks the subtree rooted at node in key order, skipping children
+// that lie outside [from, to]. It returns false once iteration should stop.
+func (t *BytesTree) ascend(node *BytesNode, from, to []byte, fn func(key []byte, value interface{}) bool) bool {
+	if node.IsLeaf {
+		i := 0
+		if from != nil {
+			i, _ = node.search(from)
+		}
+		for ; i < len(node.Suffixes); i++ {
+			key := node.key(i)
+			if to != nil && bytes.Compare(key, to) > 0 {
+				return false
+			}
+			if !fn(key, node.Values[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	i := 0
+	if from != nil {
+		i = node.childIndex(from)
+	}
+	for ; i < len(node.Children); i++ {
+		// Every key in child i sorts at or after separator i-1.
+		if i > 0 && to != nil && bytes.Compare(node.key(i-1), to) > 0 {
+			return false
+		}
+		if !t.ascend(node.Children[i], from, to, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that keys are ordered, separators route correctly, nodes
+// are within their size limits and all leaves are at the same depth.
+func (t *BytesTree) Validate() error {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	if t.Root == nil {
+		if t.Size != 0 {
+			return fmt.Errorf("empty tree has size %d", t.Size)
+		}
+		return nil
+	}
+	count, err := t.validateNode(t.Root, nil, nil, 1, true)
+	if err != nil {
+		return err
+	}
+	if count != t.Size {
+		return fmt.Errorf("tree holds %d keys but size is %d", count, t.Size)
+	}
+	return nil
+}
+
+// validateNode checks a subtree whose keys must lie in [lo, hi) and returns
+// the number of keys in its leaves.
+func (t *BytesTree) validateNode(node *BytesNode, lo, hi []byte, depth int, isRoot bool) (int, error) {
+	keys := node.keys()
+	if len(keys) > t.maxKeys() || (!isRoot && len(keys) < t.minKeys()) {
+		return 0, fmt.Errorf("node at depth %d has %d keys, outside [%d, %d]", depth, len(keys), t.minKeys(), t.maxKeys())
+	}
+	for i, key := range keys {
+		if i > 0 && bytes.Compare(keys[i-1], key) >= 0 {
+			return 0, fmt.Errorf("node at depth %d has unordered keys %q and %q", depth, keys[i-1], key)
+		}
+		if (lo != nil && bytes.Compare(key, lo) < 0) || (hi != nil && bytes.Compare(key, hi) >= 0) {
+			return 0, fmt.Errorf("key %q at depth %d is outside its separators", key, depth)
+		}
+	}
+
+	if node.IsLeaf {
+		if depth != t.Height {
+			return 0, fmt.Errorf("leaf at depth %d but height is %d", depth, t.Height)
+		}
+		if len(node.Values) != len(keys) {
+			return 0, fmt.Errorf("leaf has %d keys but %d values", len(keys), len(node.Values))
+		}
+		return len(keys), nil
+	}
+
+	if len(node.Children) != len(keys)+1 {
+		return 0, fmt.Errorf("node at depth %d has %d keys but %d children", depth, len(keys), len(node.Children))
+	}
+	total := 0
+	for i, child := range node.Children {
+		childLo, childHi := lo, hi
+		if i > 0 {
+			childLo = keys[i-1]
+		}
+		if i < len(keys) {
+			childHi = keys[i]
+		}
+		count, err := t.validateNode(child, childLo, childHi, depth+1, false)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// key returns the full key at index i.
+func (n *BytesNode) key(i int) []byte {
+	key := make([]byte, 0, len(n.Prefix)+len(n.Suffixes[i]))
+	return append(append(key, n.Prefix...), n.Suffixes[i]...)
+}
+
+// keys returns copies of all the node's full keys.
+func (n *BytesNode) keys() [][]byte {
+	keys := make([][]byte, len(n.Suffixes))
+	for i := range n.Suffixes {
+		keys[i] = n.key(i)
+	}
+	return keys
+}
+
+// setKeys replaces the node's keys, storing their longest common prefix once.
+func (n *BytesNode) setKeys(keys [][]byte) {
+	var prefix []byte
+	if len(keys) > 0 {
+		prefix = keys[0]
+		for _, key := range keys[1:] {
+			l := 0
+			for l < len(prefix) && l < len(key) && prefix[l] == key[l] {
+				l++
+			}
+			prefix = prefix[:l]
+		}
+	}
+
+	n.Prefix = append([]byte(nil), prefix...)
+	n.Suffixes = make([][]byte, len(keys))
+	for i, key := range keys {
+		n.Suffixes[i] = append([]byte(nil), key[len(prefix):]...)
+	}
+}
+
+// insertKey inserts key at index i. A key that shares the node's prefix only
+// adds its suffix; otherwise the keys are compressed again.
+func (n *BytesNode) insertKey(i int, key []byte) {
+	if len(n.Suffixes) == 0 || !bytes.HasPrefix(key, n.Prefix) {
+		n.setKeys(slices.Insert(n.keys(), i, key))
+		return
+	}
+	n.Suffixes = slices.Insert(n.Suffixes, i, append([]byte(nil), key[len(n.Prefix):]...))
+}
+
+// deleteKey removes the key at index i. The prefix is kept even if the
+// remaining keys share a longer one; it is recomputed when the node is next
+// split, merged or borrowed from.
+func (n *BytesNode) deleteKey(i int) {
+	n.Suffixes = slices.Delete(n.Suffixes, i, i+1)
+	if len(n.Suffixes) == 0 {
+		n.Prefix = nil
+	}
+}
+
+// search returns the index of the first key >= key and whether it is equal.
+// Keys that do not share the node's prefix sort before or after all of them.
+func (n *BytesNode) search(key []byte) (int, bool) {
+	if !bytes.HasPrefix(key, n.Prefix) {
+		if bytes.Compare(key, n.Prefix) < 0 {
+			return 0, false
+		}
+		return len(n.Suffixes), false
+	}
+	rest := key[len(n.Prefix):]
+	i := sort.Search(len(n.Suffixes), func(i int) bool {
+		return bytes.Compare(n.Suffixes[i], rest) >= 0
+	})
+	return i, i < len(n.Suffixes) && bytes.Equal(n.Suffixes[i], rest)
+}
+
+// childIndex returns the child of an internal node that covers key.
+func (n *BytesNode) childIndex(key []byte) int {
+	i, found := n.search(key)
+	if found {
+		i++
+	}
+	return i
+}
+
+// separator returns the shortest key s with left < s <= right, where left
+// sorts before right.
+func separator(left, right []byte) []byte {
+	l := 0
+	for l < len(left) && l < len(right) && left[l] == right[l] {
+		l++
+	}
+	return append([]byte(nil), right[:l+1]...)
+}
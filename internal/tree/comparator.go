@@ -0,0 +1,231 @@
+package tree
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Names of the built-in comparators.
+const (
+	Ascending  = "ascending"
+	Descending = "descending"
+)
+
+var (
+	comparatorsMu sync.RWMutex
+	comparators   = map[string]func(a, b int) int{
+		Ascending:  ascending,
+		Descending: descending,
+	}
+)
+
+// ascending orders keys from smallest to largest. It is the default.
+// Subtracting the keys would overflow near math.MinInt and math.MaxInt.
+func ascending(a, b int) int { return cmp.Compare(a, b) }
+
+// descending orders keys from largest to smallest.
+func descending(a, b int) int { return cmp.Compare(b, a) }
+
+// UnknownComparatorError is returned when a tree names a comparator that has
+// not been registered in this process.
+type UnknownComparatorError struct {
+	Name string
+}
+
+func (e *UnknownComparatorError) Error() string {
+	return fmt.Sprintf("comparator %q is not registered (registered: %v); register it with tree.RegisterComparator first", e.Name, ComparatorNames())
+}
+
+// comparatorSamples is the maximum number of existing keys a debug-mode
+// insert compares the new key against.
+const comparatorSamples = 12
+
+// ComparatorError is reported when a comparator is found not to define a
+// total order, which would leave the tree unsearchable.
+type ComparatorError struct {
+	Property string // "reflexivity", "antisymmetry" or "transitivity"
+	Keys     []int  // Keys for which the property fails
+}
+
+func (e *ComparatorError) Error() string {
+	return fmt.Sprintf("comparator violates %s for keys %v", e.Property, e.Keys)
+}
+
+// CheckComparator checks that cmp is reflexive, antisymmetric and transitive
+// on every combination of the given keys and returns a *ComparatorError for
+// the first violation. It takes cubic time, so keep the key set small.
+func CheckComparator(cmp func(a, b int) int, keys []int) error {
+	sign := func(a, b int) int {
+		switch c := cmp(a, b); {
+		case c < 0:
+			return -1
+		case c > 0:
+			return 1
+		}
+		return 0
+	}
+
+	for i, a := range keys {
+		if sign(a, a) != 0 {
+			return &ComparatorError{Property: "reflexivity", Keys: []int{a}}
+		}
+		for _, b := range keys[i+1:] {
+			if sign(a, b) != -sign(b, a) {
+				return &ComparatorError{Property: "antisymmetry", Keys: []int{a, b}}
+			}
+		}
+	}
+	for _, a := range keys {
+		for _, b := range keys {
+			for _, c := range keys {
+				ab, bc, ac := sign(a, b), sign(b, c), sign(a, c)
+				// a <= b <= c implies a <= c, strictly if either step is strict.
+				if ab <= 0 && bc <= 0 && (ac > 0 || (ac == 0 && (ab < 0 || bc < 0))) {
+					return &ComparatorError{Property: "transitivity", Keys: []int{a, b, c}}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkInsertOrder checks the comparator against keys along the path to the
+// position key would be inserted at when DebugComparator is set. A failure is recorded for
+// ComparatorErr and logged, and the caller must not insert the key. The caller
+// must hold the write lock.
+func (t *Tree) checkInsertOrder(key int) error {
+	if !t.DebugComparator {
+		return nil
+	}
+
+	samples := []int{key}
+	for node := t.Root; node != nil && len(samples) < comparatorSamples; {
+		i := 0
+		for i < node.Size && t.Comparator(node.Keys[i], key) < 0 {
+			i++
+		}
+		// The neighbours of the insert position plus the node's extremes.
+		for _, j := range []int{0, i - 1, i, node.Size - 1} {
+			if j >= 0 && j < node.Size && (len(samples) == 1 || samples[len(samples)-1] != node.Keys[j]) {
+				samples = append(samples, node.Keys[j])
+			}
+		}
+		if node.IsLeaf {
+			break
+		}
+		node = node.Children[i]
+	}
+
+	err := CheckComparator(t.Comparator, samples)
+	if err != nil {
+		if t.comparatorErr == nil {
+			t.comparatorErr = err
+		}
+		t.Logger.Errorf("Insert: rejected key %d: %v", key, err)
+	}
+	return err
+}
+
+// ComparatorErr returns the first *ComparatorError found by DebugComparator
+// checks, or nil if the comparator has behaved so far.
+func (t *Tree) ComparatorErr() error {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+	return t.comparatorErr
+}
+
+// RegisterComparator makes cmp available under name, so trees using it can be
+// saved and loaded again. Programs must register the same comparators before
+// loading their trees. Names cannot be registered twice.
+func RegisterComparator(name string, cmp func(a, b int) int) error {
+	if name == "" || cmp == nil {
+		return fmt.Errorf("comparator needs a name and a function")
+	}
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+
+	if _, ok := comparators[name]; ok {
+		return fmt.Errorf("comparator %q is already registered", name)
+	}
+	comparators[name] = cmp
+	return nil
+}
+
+// LookupComparator returns the comparator registered under name. The empty
+// name refers to the default ascending order.
+func LookupComparator(name string) (func(a, b int) int, error) {
+	if name == "" {
+		name = Ascending
+	}
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+
+	cmp, ok := comparators[name]
+	if !ok {
+		return nil, &UnknownComparatorError{Name: name}
+	}
+	return cmp, nil
+}
+
+// ComparatorNames returns the registered comparator names in sorted order.
+func ComparatorNames() []string {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+
+	names := make([]string, 0, len(comparators))
+	for name := range comparators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetComparator switches an empty tree to a registered comparator. The name is
+// saved with the tree so it is ordered the same way when loaded again.
+func (t *Tree) SetComparator(name string) error {
+	cmp, err := LookupComparator(name)
+	if err != nil {
+		return err
+	}
+
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	if t.Root != nil && t.Root.Size > 0 {
+		return fmt.Errorf("cannot change the comparator of a non-empty tree (size %d)", t.Size)
+	}
+	t.Comparator = cmp
+	t.ComparatorName = name
+	return nil
+}
+
+// CheckComparatorName returns an error if Comparator was assigned directly
+// rather than through SetComparator: without a ComparatorName, a saved tree
+// would load with the default order. A named comparator is not checked, as
+// loading reports names that are not registered.
+func (t *Tree) CheckComparatorName() error {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	if t.ComparatorName != "" || t.Comparator == nil {
+		return nil
+	}
+	if reflect.ValueOf(t.Comparator).Pointer() == reflect.ValueOf(ascending).Pointer() {
+		return nil
+	}
+	return fmt.Errorf("comparator has no name; register it with RegisterComparator and select it with SetComparator")
+}
+
+// ResolveComparator sets Comparator from ComparatorName, typically after the
+// tree has been decoded.
+func (t *Tree) ResolveComparator() error {
+	cmp, err := LookupComparator(t.ComparatorName)
+	if err != nil {
+		return err
+	}
+	t.Comparator = cmp
+	return nil
+}
@@ -0,0 +1,319 @@
+package tree
+
+import (
+	"container/heap"
+	"container/list"
+	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// EvictionPolicy selects which entry a size-bounded tree evicts first.
+type EvictionPolicy string
+
+// Eviction policies. The zero value behaves like EvictLRU.
+const (
+	EvictLRU      EvictionPolicy = "lru"      // Least recently inserted, updated or found
+	EvictLFU      EvictionPolicy = "lfu"      // Least frequently accessed; ties go to the least recent
+	EvictSmallest EvictionPolicy = "smallest" // Smallest key according to the comparator
+)
+
+// ParseEvictionPolicy returns the policy named s: lru, lfu or smallest. An
+// empty string is kept as is and behaves like EvictLRU.
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	switch policy := EvictionPolicy(s); policy {
+	case "", EvictLRU, EvictLFU, EvictSmallest:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown eviction policy %q (must be lru, lfu or smallest)", s)
+}
+
+// bounded reports whether MaxEntries or MaxBytes is set.
+func (t *Tree) bounded() bool {
+	return t.MaxEntries > 0 || t.MaxBytes > 0
+}
+
+// track records an inserted entry for eviction and evicts entries until the
+// tree is within its limits again. Each Insert of a duplicate key counts as
+// another entry. The caller must hold the write lock.
+func (t *Tree) track(key int, value interface{}) {
+	tracker, rebuilt := t.ensureTracker()
+	if tracker == nil {
+		return
+	}
+	if !rebuilt {
+		tracker.add(key, entrySize(key, value))
+	}
+	t.evictOverflow(tracker)
+}
+
+// trackUpdate records that an entry's value was replaced in place, then
+// evicts like track. The caller must hold the write lock.
+func (t *Tree) trackUpdate(key int, old, value interface{}) {
+	tracker, rebuilt := t.ensureTracker()
+	if tracker == nil {
+		return
+	}
+	if !rebuilt {
+		tracker.update(key, entrySize(key, value)-entrySize(key, old))
+	}
+	t.evictOverflow(tracker)
+}
+
+// evictOverflow evicts entries until the tree is within its limits. The
+// caller must hold the write lock.
+func (t *Tree) evictOverflow(tracker *evictionTracker) {
+	for (t.MaxEntries > 0 && t.Size > t.MaxEntries) || (t.MaxBytes > 0 && tracker.bytes > t.MaxBytes) {
+		victim, ok := t.evictionVictim(tracker)
+		if !ok {
+			return
+		}
+		value, _ := t.remove(victim)
+		t.Evictions++
+		t.EvictedBytes += uint64(entrySize(victim, value))
+		if t.Logger.Enabled(logger.Debug) {
+			t.Logger.Debugf("Evict: evicted key %d (%s)", victim, tracker.policy)
+		}
+		if t.OnEvict != nil {
+			t.OnEvict(victim, value)
+		}
+	}
+}
+
+// untrack forgets a removed entry. The caller must hold the write lock.
+func (t *Tree) untrack(key int, value interface{}) {
+	if t.tracker != nil {
+		t.tracker.remove(key, entrySize(key, value))
+	}
+}
+
+// touch records a read of key for LRU and LFU. It only needs the read lock.
+func (t *Tree) touch(key int) {
+	if t.tracker != nil {
+		t.tracker.touch(key)
+	}
+}
+
+// ensureTracker returns the eviction tracker, building it from the current
+// contents if limits were set or the policy changed since it was last used.
+// It returns nil for an unbounded tree, and reports whether the tracker was
+// rebuilt and so already reflects the latest write. The caller must hold the
+// write lock.
+func (t *Tree) ensureTracker() (*evictionTracker, bool) {
+	if !t.bounded() {
+		t.tracker = nil
+		return nil, false
+	}
+	policy := t.Eviction
+	if policy == "" {
+		policy = EvictLRU
+	}
+	if t.tracker != nil && t.tracker.policy == policy {
+		return t.tracker, false
+	}
+
+	t.tracker = newEvictionTracker(policy)
+	if t.Root != nil {
+		t.ascendNode(t.Root, func(key int, value interface{}) bool {
+			t.tracker.add(key, entrySize(key, value))
+			return true
+		})
+	}
+	return t.tracker, true
+}
+
+// evictionVictim picks the next entry to evict.
+func (t *Tree) evictionVictim(tracker *evictionTracker) (int, bool) {
+	if tracker.policy == EvictSmallest {
+		if t.Root == nil || t.Root.Size == 0 {
+			return 0, false
+		}
+		_, key, _ := t.getSuccessor(t.Root)
+		return key, true
+	}
+	return tracker.victim()
+}
+
+// entrySize estimates the memory used by an entry for MaxBytes.
+func entrySize(key int, value interface{}) int64 {
+	const keySize = 8
+	switch v := value.(type) {
+	case nil:
+		return keySize
+	case string:
+		return keySize + int64(len(v))
+	case []byte:
+		return keySize + int64(len(v))
+	case bool, int, int64, uint64, float64, json.Number:
+		return keySize + 8
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return keySize
+		}
+		return keySize + int64(len(data))
+	}
+}
+
+// evictionEntry is the bookkeeping kept for one key. A key inserted several
+// times has one entry counting all its occurrences.
+type evictionEntry struct {
+	key   int
+	count int           // Occurrences of key in the tree
+	freq  uint64        // Accesses, for LFU
+	seq   uint64        // Order of the last access, for LFU ties
+	index int           // Position in the LFU heap
+	elem  *list.Element // Position in the LRU list
+}
+
+// evictionTracker orders entries by the eviction policy so a victim can be
+// found without scanning the tree. Search only holds the tree's read lock, so
+// the tracker has its own mutex.
+type evictionTracker struct {
+	mu      sync.Mutex
+	policy  EvictionPolicy
+	entries map[int]*evictionEntry
+	lru     *list.List // Front is the most recently used
+	lfu     lfuHeap
+	bytes   int64
+	seq     uint64
+}
+
+func newEvictionTracker(policy EvictionPolicy) *evictionTracker {
+	return &evictionTracker{
+		policy:  policy,
+		entries: make(map[int]*evictionEntry),
+		lru:     list.New(),
+	}
+}
+
+// add records one more occurrence of key, counting it as an access.
+func (e *evictionTracker) add(key int, size int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.bytes += size
+	if entry, ok := e.entries[key]; ok {
+		entry.count++
+		e.access(entry)
+		return
+	}
+	entry := &evictionEntry{key: key, count: 1}
+	e.entries[key] = entry
+	switch e.policy {
+	case EvictLRU:
+		entry.elem = e.lru.PushFront(entry)
+	case EvictLFU:
+		e.seq++
+		entry.freq, entry.seq = 1, e.seq
+		heap.Push(&e.lfu, entry)
+	}
+}
+
+// update changes the recorded size of key by delta, counting it as an access.
+func (e *evictionTracker) update(key int, delta int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if entry, ok := e.entries[key]; ok {
+		e.bytes += delta
+		e.access(entry)
+	}
+}
+
+// touch counts an access to key if it is tracked.
+func (e *evictionTracker) touch(key int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if entry, ok := e.entries[key]; ok {
+		e.access(entry)
+	}
+}
+
+// access moves an entry according to the policy. The caller holds e.mu.
+func (e *evictionTracker) access(entry *evictionEntry) {
+	switch e.policy {
+	case EvictLRU:
+		e.lru.MoveToFront(entry.elem)
+	case EvictLFU:
+		e.seq++
+		entry.freq++
+		entry.seq = e.seq
+		heap.Fix(&e.lfu, entry.index)
+	}
+}
+
+// remove forgets one occurrence of key of the given size, and the key once
+// no occurrence is left.
+func (e *evictionTracker) remove(key int, size int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entry, ok := e.entries[key]
+	if !ok {
+		return
+	}
+	e.bytes -= size
+	if entry.count--; entry.count > 0 {
+		return
+	}
+	delete(e.entries, key)
+	switch e.policy {
+	case EvictLRU:
+		e.lru.Remove(entry.elem)
+	case EvictLFU:
+		heap.Remove(&e.lfu, entry.index)
+	}
+}
+
+// victim returns the LRU or LFU entry.
+func (e *evictionTracker) victim() (int, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch e.policy {
+	case EvictLRU:
+		if back := e.lru.Back(); back != nil {
+			return back.Value.(*evictionEntry).key, true
+		}
+	case EvictLFU:
+		if len(e.lfu) > 0 {
+			return e.lfu[0].key, true
+		}
+	}
+	return 0, false
+}
+
+// lfuHeap is a min-heap of entries by access count, then by last access.
+type lfuHeap []*evictionEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	entry := x.(*evictionEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
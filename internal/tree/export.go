@@ -0,0 +1,251 @@
+package tree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Structure is a snapshot of the tree's shape for visual exports. It is
+// encoded as JSON directly and rendered by WriteDOT and WriteASCII.
+type Structure struct {
+	Degree    int       `json:"degree"`
+	Size      int       `json:"size"`
+	Height    int       `json:"height"`
+	Highlight []int     `json:"highlight,omitempty"` // Keys whose search paths are marked
+	Root      *NodeView `json:"root"`                // Nil for an empty tree
+}
+
+// NodeView describes one node of a Structure.
+type NodeView struct {
+	ID        int         `json:"id"` // Position in breadth-first order; the root is 0
+	Level     int         `json:"level"`
+	Keys      []int       `json:"keys"`
+	MaxKeys   int         `json:"maxKeys"`
+	MinKeys   int         `json:"minKeys"`
+	Fill      float64     `json:"fill"`                // Keys held divided by MaxKeys
+	Underfull bool        `json:"underfull,omitempty"` // A non-root node below MinKeys
+	Leaf      bool        `json:"leaf"`
+	Path      []int       `json:"path,omitempty"`  // Highlighted keys whose search visits this node
+	Found     []int       `json:"found,omitempty"` // Highlighted keys held by this node
+	Children  []*NodeView `json:"children,omitempty"`
+}
+
+// Structure returns a snapshot of the tree's nodes with their parent-child
+// links and fill levels. The search path of each highlight key is marked, so
+// a rebalancing bug can be traced from the root to the node that holds (or
+// should hold) the key.
+func (t *Tree) Structure(highlight ...int) Structure {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	s := Structure{Degree: t.Degree, Size: t.Size, Height: t.Height, Highlight: highlight}
+	if t.Root == nil || (t.Root.Size == 0 && t.Root.IsLeaf) {
+		return s
+	}
+
+	type queued struct {
+		node   *Node
+		parent *NodeView
+	}
+	views := make(map[*Node]*NodeView)
+	queue := []queued{{node: t.Root}}
+	for id := 0; len(queue) > 0; id++ {
+		node, parent := queue[0].node, queue[0].parent
+		queue = queue[1:]
+
+		view := &NodeView{
+			ID:        id,
+			Keys:      append([]int(nil), node.Keys[:node.Size]...),
+			MaxKeys:   node.MaxKeys,
+			MinKeys:   node.MinKeys,
+			Underfull: parent != nil && node.Size < node.MinKeys,
+			Leaf:      node.IsLeaf,
+		}
+		if node.MaxKeys > 0 {
+			view.Fill = float64(node.Size) / float64(node.MaxKeys)
+		}
+		if parent != nil {
+			view.Level = parent.Level + 1
+			parent.Children = append(parent.Children, view)
+		}
+		views[node] = view
+
+		if !node.IsLeaf {
+			for _, child := range node.Children {
+				queue = append(queue, queued{child, view})
+			}
+		}
+	}
+	s.Root = views[t.Root]
+
+	for _, key := range highlight {
+		t.markPath(views, key)
+	}
+	return s
+}
+
+// markPath marks the nodes a search for key visits. The caller holds the read
+// lock.
+func (t *Tree) markPath(views map[*Node]*NodeView, key int) {
+	node := t.Root
+	for node != nil {
+		view := views[node]
+		view.Path = append(view.Path, key)
+		i := 0
+		for i < node.Size && t.Comparator(node.Keys[i], key) < 0 {
+			i++
+		}
+		if i < node.Size && t.Comparator(node.Keys[i], key) == 0 {
+			view.Found = append(view.Found, key)
+			return
+		}
+		if node.IsLeaf {
+			return
+		}
+		node = node.Children[i]
+	}
+}
+
+// WriteDOT writes the tree in Graphviz DOT format; render it with, for
+// example, `dot -Tsvg`. Each node is a record of its keys with ports between
+// them for the child edges, followed by its fill. Underfull nodes are red and
+// full nodes yellow; the search paths of the highlight keys are drawn in
+// bold blue, and the keys found are bracketed.
+func (t *Tree) WriteDOT(w io.Writer, highlight ...int) error {
+	return t.Structure(highlight...).WriteDOT(w)
+}
+
+// WriteASCII writes the tree as an indented outline, one node per line with
+// its keys and fill, marking the search paths of the highlight keys.
+func (t *Tree) WriteASCII(w io.Writer, highlight ...int) error {
+	return t.Structure(highlight...).WriteASCII(w)
+}
+
+// WriteDOT writes the structure in Graphviz DOT format (see Tree.WriteDOT).
+func (s Structure) WriteDOT(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "digraph btree {\n")
+	fmt.Fprintf(b, "\tlabel=%q;\n", s.title())
+	fmt.Fprintf(b, "\tnode [shape=record, style=filled, fillcolor=\"#d9ead3\", fontname=\"monospace\"];\n")
+	fmt.Fprintf(b, "\tedge [arrowsize=0.6];\n")
+	if s.Root != nil {
+		s.Root.walk(func(n *NodeView) {
+			attrs := []string{"label=" + strconv.Quote(n.dotLabel())}
+			switch {
+			case n.Underfull:
+				attrs = append(attrs, `fillcolor="#f4cccc"`)
+			case n.Fill >= 1:
+				attrs = append(attrs, `fillcolor="#fff2cc"`)
+			}
+			if len(n.Path) > 0 {
+				attrs = append(attrs, `color="#1155cc"`, "penwidth=2.5")
+			}
+			fmt.Fprintf(b, "\tn%d [%s];\n", n.ID, strings.Join(attrs, ", "))
+			for i, child := range n.Children {
+				fmt.Fprintf(b, "\tn%d:c%d -> n%d", n.ID, i, child.ID)
+				if len(child.Path) > 0 {
+					fmt.Fprintf(b, ` [color="#1155cc", penwidth=2.5]`)
+				}
+				fmt.Fprintf(b, ";\n")
+			}
+		})
+	}
+	fmt.Fprintf(b, "}\n")
+	return b.Flush()
+}
+
+// WriteASCII writes the structure as an indented outline (see
+// Tree.WriteASCII).
+func (s Structure) WriteASCII(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, s.title())
+	if s.Root != nil {
+		s.Root.writeASCII(b, "", "")
+	}
+	return b.Flush()
+}
+
+// title summarizes the tree and the highlighted keys.
+func (s Structure) title() string {
+	title := fmt.Sprintf("Tree (degree=%d, size=%d, height=%d)", s.Degree, s.Size, s.Height)
+	if len(s.Highlight) > 0 {
+		title += fmt.Sprintf(" search path of %s", joinInts(s.Highlight, ", "))
+	}
+	return title
+}
+
+// walk calls fn for n and its descendants in depth-first order.
+func (n *NodeView) walk(fn func(n *NodeView)) {
+	fn(n)
+	for _, child := range n.Children {
+		child.walk(fn)
+	}
+}
+
+// dotLabel returns n's record label: keys interleaved with child ports
+// above the fill.
+func (n *NodeView) dotLabel() string {
+	fields := make([]string, 0, 2*len(n.Keys)+1)
+	for i, key := range n.Keys {
+		if !n.Leaf {
+			fields = append(fields, fmt.Sprintf("<c%d>", i))
+		}
+		fields = append(fields, n.keyText(key))
+	}
+	if !n.Leaf {
+		fields = append(fields, fmt.Sprintf("<c%d>", len(n.Keys)))
+	}
+	return fmt.Sprintf("{{%s}|%s}", strings.Join(fields, "|"), n.fillText())
+}
+
+// writeASCII writes n and its subtree; prefix starts n's line and indent its
+// children's lines.
+func (n *NodeView) writeASCII(b *bufio.Writer, prefix, indent string) {
+	keys := make([]string, len(n.Keys))
+	for i, key := range n.Keys {
+		keys[i] = n.keyText(key)
+	}
+	fmt.Fprintf(b, "%s(%s) %s", prefix, strings.Join(keys, " "), n.fillText())
+	if n.Underfull {
+		b.WriteString(" underfull")
+	}
+	if len(n.Path) > 0 {
+		fmt.Fprintf(b, "  <- %s", joinInts(n.Path, ", "))
+	}
+	b.WriteByte('\n')
+
+	for i, child := range n.Children {
+		if i == len(n.Children)-1 {
+			child.writeASCII(b, indent+"`-- ", indent+"    ")
+		} else {
+			child.writeASCII(b, indent+"|-- ", indent+"|   ")
+		}
+	}
+}
+
+// keyText formats key, bracketed if it is a highlighted key found in n.
+func (n *NodeView) keyText(key int) string {
+	for _, found := range n.Found {
+		if found == key {
+			return "[" + strconv.Itoa(key) + "]"
+		}
+	}
+	return strconv.Itoa(key)
+}
+
+// fillText formats n's fill as keys/capacity and a percentage.
+func (n *NodeView) fillText() string {
+	return fmt.Sprintf("%d/%d %.0f%%", len(n.Keys), n.MaxKeys, n.Fill*100)
+}
+
+// joinInts formats ints separated by sep.
+func joinInts(ints []int, sep string) string {
+	s := make([]string, len(ints))
+	for i, v := range ints {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, sep)
+}
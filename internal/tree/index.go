@@ -0,0 +1,227 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// IndexFunc maps a value to its secondary key. Returning false leaves the
+// entry out of the index.
+type IndexFunc func(value interface{}) (int, bool)
+
+// Index is a secondary index: a tree from secondary keys to the ascending
+// primary keys of the entries that map to them. It is saved with its tree,
+// but its IndexFunc is not and must be attached again with AddIndex.
+type Index struct {
+	Tree  *Tree `json:"tree"`            // Secondary key -> []int primary keys
+	Stale bool  `json:"stale,omitempty"` // Missed writes while detached; rebuilt by AddIndex
+
+	keyFunc IndexFunc
+}
+
+// UnmarshalJSON decodes a saved index, keeping primary keys as json.Number
+// so keys beyond 2^53 survive the round trip; the tree's own values are
+// decoded as usual.
+func (i *Index) UnmarshalJSON(data []byte) error {
+	type plain Index // Without the UnmarshalJSON method
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode((*plain)(i))
+}
+
+// AddIndex defines a secondary index on the tree and builds it from the
+// current contents. For an index loaded from disk it attaches keyFunc and
+// reuses the saved index unless writes happened while it was detached.
+func (t *Tree) AddIndex(name string, keyFunc IndexFunc) error {
+	if name == "" || keyFunc == nil {
+		return fmt.Errorf("index needs a name and a key function")
+	}
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	if index, ok := t.Indexes[name]; ok {
+		if index.keyFunc != nil {
+			return fmt.Errorf("index %q already exists", name)
+		}
+		index.keyFunc = keyFunc
+		index.Tree.Logger = t.Logger // Not saved with the index
+		if !index.Stale {
+			t.Logger.Infof("AddIndex: attached saved index %q (%d secondary keys)", name, index.Tree.Size)
+			return nil
+		}
+	} else {
+		if t.Indexes == nil {
+			t.Indexes = make(map[string]*Index)
+		}
+		t.Indexes[name] = &Index{keyFunc: keyFunc}
+	}
+	t.rebuildIndex(name)
+	return nil
+}
+
+// DropIndex removes a secondary index.
+func (t *Tree) DropIndex(name string) error {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	if _, ok := t.Indexes[name]; !ok {
+		return fmt.Errorf("index %q does not exist", name)
+	}
+	delete(t.Indexes, name)
+	return nil
+}
+
+// LookupBy returns the primary keys, in ascending order, of the entries whose
+// secondary key in the named index equals key.
+func (t *Tree) LookupBy(name string, key int) ([]int, error) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	index, err := t.readableIndex(name)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := index.Tree.Search(key)
+	return primaryKeys(value), nil
+}
+
+// AscendIndex calls fn for every entry whose secondary key in the named index
+// lies in [from, to], ordered by secondary key and then primary key, until fn
+// returns false.
+func (t *Tree) AscendIndex(name string, from, to int, fn func(secondary, primary int, value interface{}) bool) error {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	index, err := t.readableIndex(name)
+	if err != nil {
+		return err
+	}
+	index.Tree.AscendRange(from, to, func(secondary int, keys interface{}) bool {
+		for _, primary := range primaryKeys(keys) {
+			node, i := t.findKey(t.Root, primary)
+			if node == nil {
+				continue
+			}
+			if !fn(secondary, primary, node.Values[i]) {
+				return false
+			}
+		}
+		return true
+	})
+	return nil
+}
+
+// readableIndex returns the named index if it is up to date. The caller must
+// hold the lock.
+func (t *Tree) readableIndex(name string) (*Index, error) {
+	index, ok := t.Indexes[name]
+	if !ok {
+		return nil, fmt.Errorf("index %q does not exist", name)
+	}
+	if index.keyFunc == nil || index.Stale {
+		return nil, fmt.Errorf("index %q has no key function; call AddIndex after loading the tree", name)
+	}
+	return index, nil
+}
+
+// indexAdd records an entry in every index. The caller must hold the write
+// lock.
+func (t *Tree) indexAdd(key int, value interface{}) {
+	for _, index := range t.Indexes {
+		if index.keyFunc == nil {
+			index.Stale = true
+			continue
+		}
+		secondary, ok := index.keyFunc(value)
+		if !ok {
+			continue
+		}
+		keys := primaryKeys(lookup(index.Tree, secondary))
+		if i, found := slices.BinarySearch(keys, key); !found {
+			index.Tree.Put(secondary, slices.Insert(slices.Clone(keys), i, key))
+		}
+	}
+}
+
+// indexRemove removes an entry from every index. The caller must hold the
+// write lock.
+func (t *Tree) indexRemove(key int, value interface{}) {
+	for _, index := range t.Indexes {
+		if index.keyFunc == nil {
+			index.Stale = true
+			continue
+		}
+		secondary, ok := index.keyFunc(value)
+		if !ok {
+			continue
+		}
+		keys := primaryKeys(lookup(index.Tree, secondary))
+		i, found := slices.BinarySearch(keys, key)
+		switch {
+		case !found:
+		case len(keys) == 1:
+			index.Tree.Remove(secondary)
+		default:
+			index.Tree.Put(secondary, slices.Delete(slices.Clone(keys), i, i+1))
+		}
+	}
+}
+
+// rebuildIndex rebuilds an index from the tree's contents. The caller must
+// hold the write lock.
+func (t *Tree) rebuildIndex(name string) {
+	index := t.Indexes[name]
+	index.Tree = NewTree(t.Degree, t.Logger)
+	index.Stale = false
+	if t.Root != nil {
+		t.ascendNode(t.Root, func(key int, value interface{}) bool {
+			if secondary, ok := index.keyFunc(value); ok {
+				keys := primaryKeys(lookup(index.Tree, secondary))
+				index.Tree.Put(secondary, append(slices.Clone(keys), key))
+			}
+			return true
+		})
+	}
+	t.Logger.Infof("AddIndex: built index %q (%d secondary keys)", name, index.Tree.Size)
+}
+
+// rebuildIndexes rebuilds every attached index and marks detached ones stale,
+// after the tree's contents were replaced wholesale. The caller must hold the
+// write lock.
+func (t *Tree) rebuildIndexes() {
+	for name, index := range t.Indexes {
+		if index.keyFunc == nil {
+			index.Stale = true
+			continue
+		}
+		t.rebuildIndex(name)
+	}
+}
+
+// lookup returns the value stored under key in an index tree, or nil.
+func lookup(index *Tree, key int) interface{} {
+	value, _ := index.Search(key)
+	return value
+}
+
+// primaryKeys converts an index value to primary keys. Indexes loaded from
+// disk hold decoded JSON arrays of json.Number rather than []int.
+func primaryKeys(value interface{}) []int {
+	switch v := value.(type) {
+	case []int:
+		return v
+	case []interface{}:
+		keys := make([]int, 0, len(v))
+		for _, item := range v {
+			if n, ok := item.(json.Number); ok {
+				if i, err := n.Int64(); err == nil {
+					keys = append(keys, int(i))
+				}
+			}
+		}
+		return keys
+	}
+	return nil
+}
@@ -1,7 +1,42 @@
 package tree
 
-// checkInvariants recursively asserts that each non-leaf node has one more child than its key count.
+import "fmt"
+
+// InvariantMode selects how much of the tree checkInvariants examines after
+// structural changes. The zero value "" means InvariantsLocal; any other
+// value outside the constants below is rejected by ParseInvariantMode and
+// Configure, and panics on the first check if assigned to Tree.Invariants
+// directly.
+type InvariantMode string
+
+const (
+	InvariantsOff   InvariantMode = "off"   // No checks
+	InvariantsLocal InvariantMode = "local" // Only the nodes an operation touched (default)
+	InvariantsFull  InvariantMode = "full"  // The whole subtree below each touched node; O(n) per insert, for tests
+)
+
+// ParseInvariantMode returns the mode named s: off, local or full. An empty
+// string selects the default, InvariantsLocal.
+func ParseInvariantMode(s string) (InvariantMode, error) {
+	switch mode := InvariantMode(s); mode {
+	case "":
+		return InvariantsLocal, nil
+	case InvariantsOff, InvariantsLocal, InvariantsFull:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown invariant mode %q (must be off, local or full)", s)
+}
+
+// checkInvariants asserts that each non-leaf node has one more child than its
+// key count, for node alone or, in full mode, for its whole subtree.
 func (t *Tree) checkInvariants(node *Node) {
+    switch t.Invariants {
+    case InvariantsOff:
+        return
+    case "", InvariantsLocal, InvariantsFull:
+    default:
+        t.Logger.Panicf("unknown invariant mode %q (must be off, local or full)", t.Invariants)
+    }
     if node == nil {
         return
     }
@@ -10,6 +45,9 @@ func (t *Tree) checkInvariants(node *Node) {
             t.Logger.Panicf("Invariant violation: node %v has %d keys but %d children (expected %d)",
                 node.Keys, node.Size, len(node.Children), node.Size+1)
         }
+        if t.Invariants != InvariantsFull {
+            return
+        }
         for _, child := range node.Children {
             t.checkInvariants(child)
         }
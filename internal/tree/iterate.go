@@ -0,0 +1,106 @@
+package tree
+
+// Ascend calls fn for every key-value pair in key order until fn returns false.
+func (t *Tree) Ascend(fn func(key int, value interface{}) bool) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	t.ascendNode(t.Root, fn)
+}
+
+// ascendNode performs an in-order traversal of a subtree. It returns false if
+// the traversal was stopped by fn.
+func (t *Tree) ascendNode(node *Node, fn func(key int, value interface{}) bool) bool {
+	if node == nil {
+		return true
+	}
+
+	for i := 0; i < node.Size; i++ {
+		if !node.IsLeaf && !t.ascendNode(node.Children[i], fn) {
+			return false
+		}
+		if !fn(node.Keys[i], node.Values[i]) {
+			return false
+		}
+	}
+	if !node.IsLeaf && len(node.Children) > node.Size {
+		return t.ascendNode(node.Children[node.Size], fn)
+	}
+	return true
+}
+
+// AscendRange calls fn for every pair with from <= key <= to, in key order,
+// until fn returns false. Bounds are interpreted using the tree's comparator.
+func (t *Tree) AscendRange(from, to int, fn func(key int, value interface{}) bool) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	t.ascendRangeNode(t.Root, from, to, fn)
+}
+
+// ascendRangeNode walks only the children that can hold keys in [from, to].
+// It returns false once the traversal is finished or stopped by fn.
+func (t *Tree) ascendRangeNode(node *Node, from, to int, fn func(key int, value interface{}) bool) bool {
+	if node == nil {
+		return true
+	}
+
+	for i := 0; i < node.Size; i++ {
+		key := node.Keys[i]
+		aboveFrom := t.Comparator(key, from) >= 0
+		if aboveFrom && !node.IsLeaf && !t.ascendRangeNode(node.Children[i], from, to, fn) {
+			return false
+		}
+		if t.Comparator(key, to) > 0 {
+			return false
+		}
+		if aboveFrom && !fn(key, node.Values[i]) {
+			return false
+		}
+	}
+	if !node.IsLeaf && len(node.Children) > node.Size {
+		return t.ascendRangeNode(node.Children[node.Size], from, to, fn)
+	}
+	return true
+}
+
+// Rank returns the number of keys that sort before key and whether key is
+// present. It walks the keys in order, so it runs in time proportional to the
+// rank.
+func (t *Tree) Rank(key int) (int, bool) {
+	rank, found := 0, false
+	t.Ascend(func(k int, _ interface{}) bool {
+		cmp := t.Comparator(k, key)
+		if cmp >= 0 {
+			found = cmp == 0
+			return false
+		}
+		rank++
+		return true
+	})
+	return rank, found
+}
+
+// Min returns the smallest key and its value, or false if the tree is empty.
+func (t *Tree) Min() (int, interface{}, bool) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	if t.Root == nil || t.Root.Size == 0 {
+		return 0, nil, false
+	}
+	_, key, value := t.getSuccessor(t.Root)
+	return key, value, true
+}
+
+// Max returns the largest key and its value, or false if the tree is empty.
+func (t *Tree) Max() (int, interface{}, bool) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	if t.Root == nil || t.Root.Size == 0 {
+		return 0, nil, false
+	}
+	_, key, value := t.getPredecessor(t.Root)
+	return key, value, true
+}
@@ -0,0 +1,33 @@
+package tree
+
+import (
+	"elastic-btree/pkg/logger"
+	"fmt"
+	"time"
+)
+
+// opStart returns the start time of an operation whose summary will be
+// logged, or the zero time if Debug messages are filtered out.
+func (t *Tree) opStart() time.Time {
+	if !t.Logger.Enabled(logger.Debug) {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// logOp logs an operation summary with the op, key, node and duration as
+// fields. node is the node that held or received the key, or nil.
+func (t *Tree) logOp(op string, key int, node *Node, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	t.Logger.Debug(op, "op", op, "key", key, "node", nodeID(node), "duration", time.Since(start))
+}
+
+// nodeID identifies a node in log fields for as long as it is in memory.
+func nodeID(node *Node) string {
+	if node == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%p", node)
+}
@@ -0,0 +1,73 @@
+package tree
+
+import (
+	"elastic-btree/pkg/metrics"
+	"time"
+)
+
+// Operation names used as the op label of tree metrics.
+const (
+	metricInsert = "insert"
+	metricSearch = "search"
+	metricDelete = "delete"
+)
+
+// treeMetrics holds the per-operation metrics of an instrumented tree.
+type treeMetrics struct {
+	ops map[string]*opMetrics
+}
+
+// opMetrics counts one kind of operation and records its latency.
+type opMetrics struct {
+	total   metrics.Counter
+	latency *metrics.Histogram
+}
+
+// observe records an operation that started at start.
+func (m *opMetrics) observe(start time.Time) {
+	m.total.Inc()
+	m.latency.ObserveSince(start)
+}
+
+// Instrument registers the tree's metrics with reg: operation counts and
+// latencies for Insert/Put, Search and Delete/Remove, rebalancing counts,
+// and size and height gauges. Uninstrumented trees pay only a nil check per
+// operation.
+func (t *Tree) Instrument(reg *metrics.Registry) {
+	m := &treeMetrics{ops: make(map[string]*opMetrics)}
+	for _, op := range []string{metricInsert, metricSearch, metricDelete} {
+		om := &opMetrics{latency: metrics.NewHistogram(nil)}
+		m.ops[op] = om
+		reg.Counter("elastic_btree_operations_total", "Tree operations by type.", &om.total, "op", op)
+		reg.Histogram("elastic_btree_operation_duration_seconds", "Tree operation latency once the lock is held.", om.latency, "op", op)
+	}
+
+	rebalances := map[string]*uint64{"split": &t.splits, "merge": &t.merges, "borrow": &t.borrows}
+	for event, counter := range rebalances {
+		reg.CounterFunc("elastic_btree_rebalances_total", "Node splits, merges and borrows.", func() float64 {
+			t.Lock.RLock()
+			defer t.Lock.RUnlock()
+			return float64(*counter)
+		}, "event", event)
+	}
+	reg.GaugeFunc("elastic_btree_keys", "Number of keys in the tree.", func() float64 {
+		t.Lock.RLock()
+		defer t.Lock.RUnlock()
+		return float64(t.Size)
+	})
+	reg.GaugeFunc("elastic_btree_height", "Height of the tree.", func() float64 {
+		t.Lock.RLock()
+		defer t.Lock.RUnlock()
+		return float64(t.Height)
+	})
+
+	t.Lock.Lock()
+	t.metrics = m
+	t.Lock.Unlock()
+}
+
+// observe records an operation that started at start. The caller must have
+// checked that the tree is instrumented.
+func (m *treeMetrics) observe(op string, start time.Time) {
+	m.ops[op].observe(start)
+}
@@ -12,4 +12,9 @@ type Node struct {
    // Next     *Node          `json:"-"`         // For B+ Trees
     Values   []interface{}  `json:"values"` // For key-value pairs
   //  Metadata map[string]interface{}
+
+    // Access counters for the sizing policy, reset when a leaf splits.
+    inserts  int
+    appends  int
+    updates  int
 }
\ No newline at end of file
@@ -0,0 +1,104 @@
+package tree
+
+import (
+	"elastic-btree/pkg/logger"
+	"fmt"
+)
+
+// rebuildAttempts is how many times Rebuild copies the tree without blocking
+// writers before it copies under the write lock instead.
+const rebuildAttempts = 3
+
+// Rebuild rebuilds the tree with a new degree, replacing the MaxKeys and
+// MinKeys baked into every node. Its contents are copied under the read lock,
+// and the new nodes are then built without holding any lock, so reads and
+// writes continue against the old nodes; the new root is swapped in under the
+// write lock. If writes raced with the build it is retried, and the last
+// attempt holds the write lock throughout.
+func (t *Tree) Rebuild(newDegree int) error {
+	if newDegree < 2 {
+		return fmt.Errorf("degree must be at least 2, got %d", newDegree)
+	}
+
+	for attempt := 1; attempt <= rebuildAttempts; attempt++ {
+		if attempt == rebuildAttempts {
+			t.Lock.Lock()
+			rebuilt, err := t.contents().rebuilt(newDegree)
+			if err == nil {
+				t.swapRoot(rebuilt)
+			}
+			t.Lock.Unlock()
+			return err
+		}
+
+		t.Lock.RLock()
+		version := t.Version
+		snapshot := t.contents()
+		t.Lock.RUnlock()
+		rebuilt, err := snapshot.rebuilt(newDegree)
+		if err != nil {
+			return err
+		}
+
+		t.Lock.Lock()
+		if t.Version == version {
+			t.swapRoot(rebuilt)
+			t.Lock.Unlock()
+			return nil
+		}
+		t.Lock.Unlock()
+		t.Logger.Infof("Rebuild: tree changed during attempt %d, retrying", attempt)
+	}
+	return nil
+}
+
+// rebuildSnapshot is a copy of a tree's pairs in key order, with what is
+// needed to build a new tree from them.
+type rebuildSnapshot struct {
+	keys       []int
+	values     []interface{}
+	comparator func(a, b int) int
+	logger     *logger.Logger
+}
+
+// contents copies the tree's pairs in key order. The caller must hold the
+// lock.
+func (t *Tree) contents() rebuildSnapshot {
+	s := rebuildSnapshot{
+		keys:       make([]int, 0, t.Size),
+		values:     make([]interface{}, 0, t.Size),
+		comparator: t.Comparator,
+		logger:     t.Logger,
+	}
+	if t.Root != nil {
+		t.ascendNode(t.Root, func(key int, value interface{}) bool {
+			s.keys = append(s.keys, key)
+			s.values = append(s.values, value)
+			return true
+		})
+	}
+	return s
+}
+
+// rebuilt bulk-loads the snapshot into a new tree of the given degree. It
+// needs no lock on the source tree.
+func (s rebuildSnapshot) rebuilt(degree int) (*Tree, error) {
+	rebuilt := NewTree(degree, s.logger)
+	rebuilt.Comparator = s.comparator
+	if err := rebuilt.BulkLoad(s.keys, s.values); err != nil {
+		return nil, fmt.Errorf("rebuild with degree %d: %v", degree, err)
+	}
+	return rebuilt, nil
+}
+
+// swapRoot installs the nodes of a rebuilt tree. The contents are unchanged,
+// so expiry times, eviction order and indexes stay valid. The caller must
+// hold the write lock.
+func (t *Tree) swapRoot(rebuilt *Tree) {
+	old := t.Degree
+	t.Root = rebuilt.Root
+	t.Degree = rebuilt.Degree
+	t.Height = rebuilt.Height
+	t.Size = rebuilt.Size
+	t.Logger.Infof("Rebuild: degree %d -> %d, height %d", old, t.Degree, t.Height)
+}
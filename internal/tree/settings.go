@@ -0,0 +1,49 @@
+package tree
+
+// Settings are the options of a tree that may change while it is in use.
+type Settings struct {
+	Invariants InvariantMode  // How much structure to check after changes
+	MaxEntries int            // Evict once Size exceeds this (0: unbounded)
+	MaxBytes   int64          // Evict once the estimated entry size exceeds this (0: unbounded)
+	Eviction   EvictionPolicy // Which entry to evict first
+}
+
+// Settings returns the tree's current runtime options.
+func (t *Tree) Settings() Settings {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	return Settings{
+		Invariants: t.Invariants,
+		MaxEntries: t.MaxEntries,
+		MaxBytes:   t.MaxBytes,
+		Eviction:   t.Eviction,
+	}
+}
+
+// Configure applies s under the write lock, so every operation sees either
+// the old or the new options, never a mix. Entries beyond lowered limits are
+// evicted before it returns. An unknown invariant mode or eviction policy is
+// rejected and nothing is applied; an empty invariant mode selects
+// InvariantsLocal.
+func (t *Tree) Configure(s Settings) error {
+	invariants, err := ParseInvariantMode(string(s.Invariants))
+	if err != nil {
+		return err
+	}
+	if _, err := ParseEvictionPolicy(string(s.Eviction)); err != nil {
+		return err
+	}
+
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	t.Invariants = invariants
+	t.MaxEntries = s.MaxEntries
+	t.MaxBytes = s.MaxBytes
+	t.Eviction = s.Eviction
+	if tracker, _ := t.ensureTracker(); tracker != nil {
+		t.evictOverflow(tracker)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package tree
+
+// SplitStats describes a full leaf that is about to be split. Counters cover
+// the time since the leaf was created by the previous split.
+type SplitStats struct {
+	Degree  int // Degree of the tree
+	MaxKeys int // Capacity of the leaf being split
+	Inserts int // Keys inserted into the leaf
+	Appends int // Inserts that landed after the leaf's last key
+	Updates int // Values replaced in place by Put
+}
+
+// SizingPolicy decides the capacity (MaxKeys) of the two leaves created when
+// a full leaf splits, so node sizes can follow the workload. The tree clamps
+// the result to what the two halves can validly hold. Growing a leaf keeps
+// MinKeys at Degree-1; shrinking one below 2*Degree-1 lowers it to match.
+type SizingPolicy interface {
+	LeafCapacity(stats SplitStats) int
+}
+
+// FixedSizing gives every node 2*Degree-1 keys. It is the default.
+type FixedSizing struct{}
+
+// LeafCapacity implements SizingPolicy.
+func (FixedSizing) LeafCapacity(stats SplitStats) int {
+	return 2*stats.Degree - 1
+}
+
+// AdaptiveSizing doubles the capacity of leaves that receive sequential
+// appends, so bulk ingestion splits less often. It also halves the capacity of
+// leaves dominated by in-place updates, which need no spare room; this saves
+// no measurable time. Capacities stay within [MinKeys, MaxKeys]; zero values
+// default to Degree-1 and 16 times the default capacity.
+type AdaptiveSizing struct {
+	MinKeys int // Smallest leaf capacity
+	MaxKeys int // Largest leaf capacity
+}
+
+// LeafCapacity implements SizingPolicy.
+func (p AdaptiveSizing) LeafCapacity(stats SplitStats) int {
+	lower, upper := p.MinKeys, p.MaxKeys
+	if lower <= 0 {
+		lower = stats.Degree - 1
+	}
+	if upper <= 0 {
+		upper = 16 * (2*stats.Degree - 1)
+	}
+
+	capacity := stats.MaxKeys
+	switch {
+	case stats.Inserts >= stats.MaxKeys/2 && stats.Appends*10 >= stats.Inserts*9:
+		capacity *= 2
+	case stats.Updates > 2*stats.Inserts:
+		capacity /= 2
+	}
+	return min(max(capacity, lower), upper)
+}
+
+// leafCapacity asks the sizing policy for the capacity of the halves of a
+// splitting leaf and clamps it so that halves of left and right keys are
+// valid.
+func (t *Tree) leafCapacity(node *Node, left, right int) int {
+	var policy SizingPolicy = FixedSizing{}
+	if t.Sizing != nil {
+		policy = t.Sizing
+	}
+	capacity := policy.LeafCapacity(SplitStats{
+		Degree:  t.Degree,
+		MaxKeys: node.MaxKeys,
+		Inserts: node.inserts,
+		Appends: node.appends,
+		Updates: node.updates,
+	})
+	// Each half must fit with room for the key being inserted, leave room
+	// for three keys so it can split again, and hold at least MinKeys keys.
+	capacity = max(capacity, left+1, right+1, 3)
+	if t.minKeysFor(capacity) > right {
+		capacity = 2*right + 1
+	}
+	return capacity
+}
+
+// minKeysFor returns the minimum key count of a node with the given capacity:
+// Degree-1, lowered for nodes too small to split into two such halves.
+func (t *Tree) minKeysFor(capacity int) int {
+	return min(t.Degree-1, (capacity-1)/2)
+}
@@ -0,0 +1,81 @@
+package tree
+
+import "unsafe"
+
+// FillBuckets is the number of buckets in Stats.FillHistogram.
+const FillBuckets = 10
+
+// Stats describes the shape of a tree and its rebalancing activity.
+type Stats struct {
+	Size          int              `json:"size"`          // Number of keys
+	Height        int              `json:"height"`        // Number of levels
+	Degree        int              `json:"degree"`        // Minimum degree
+	Nodes         int              `json:"nodes"`         // Total node count
+	Leaves        int              `json:"leaves"`        // Leaf node count
+	Internal      int              `json:"internal"`      // Internal node count
+	LevelNodes    []int            `json:"levelNodes"`    // Node count per level, root first
+	FillFactor    float64          `json:"fillFactor"`    // Keys held divided by key capacity
+	FillHistogram [FillBuckets]int `json:"fillHistogram"` // Nodes by fill (Size/MaxKeys) in 10% buckets
+	Bytes         int64            `json:"bytes"`         // Estimated memory used by nodes and entries
+	Splits        uint64           `json:"splits"`        // Node splits since the tree was created or loaded
+	Merges        uint64           `json:"merges"`        // Node merges since the tree was created or loaded
+	Borrows       uint64           `json:"borrows"`       // Keys borrowed from siblings since the tree was created or loaded
+}
+
+// Stats walks the tree level by level and returns its statistics.
+func (t *Tree) Stats() Stats {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	stats := Stats{
+		Size:    t.Size,
+		Height:  t.Height,
+		Degree:  t.Degree,
+		Splits:  t.splits,
+		Merges:  t.merges,
+		Borrows: t.borrows,
+	}
+	if t.Root == nil {
+		return stats
+	}
+
+	capacity := 0
+	queue := []*Node{t.Root}
+	for len(queue) > 0 {
+		stats.LevelNodes = append(stats.LevelNodes, len(queue))
+		var next []*Node
+		for _, node := range queue {
+			stats.Nodes++
+			if node.IsLeaf {
+				stats.Leaves++
+			} else {
+				stats.Internal++
+				next = append(next, node.Children...)
+			}
+			capacity += node.MaxKeys
+			if node.MaxKeys > 0 {
+				bucket := node.Size * FillBuckets / node.MaxKeys
+				stats.FillHistogram[min(bucket, FillBuckets-1)]++
+			}
+			stats.Bytes += nodeSize(node)
+		}
+		queue = next
+	}
+	if capacity > 0 {
+		stats.FillFactor = float64(t.Size) / float64(capacity)
+	}
+	return stats
+}
+
+// nodeSize estimates the memory used by a node and its entries.
+func nodeSize(node *Node) int64 {
+	size := int64(unsafe.Sizeof(*node))
+	size += int64(cap(node.Keys)) * int64(unsafe.Sizeof(int(0)))
+	size += int64(cap(node.Values)) * int64(unsafe.Sizeof(interface{}(nil)))
+	size += int64(cap(node.Children)) * int64(unsafe.Sizeof(node))
+	for i, key := range node.Keys {
+		// Keys are already counted in the slice above.
+		size += entrySize(key, node.Values[i]) - 8
+	}
+	return size
+}
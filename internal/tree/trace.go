@@ -0,0 +1,66 @@
+package tree
+
+import "time"
+
+// OpSearch names lookups in Tracer callbacks; writes use the change event
+// operations OpInsert and OpDelete.
+const OpSearch = "search"
+
+// Tracer receives callbacks from tree operations, for example to record
+// spans or profile rebalancing. Search holds only the read lock, so callbacks
+// can run concurrently and must be safe for concurrent use. They run with
+// the tree's lock held and must not call back into the tree.
+type Tracer interface {
+	// OpStart is called when Insert, Put, Search, Delete or Remove starts
+	// and returns a function called when it ends, or nil.
+	OpStart(op string, key int) (end func())
+	// Visit is called for every node examined while descending the tree.
+	Visit(node *Node)
+	// Split is called after a full node was split into node and sibling.
+	Split(node, sibling *Node)
+	// Merge is called after sibling was merged into node.
+	Merge(node, sibling *Node)
+	// Borrow is called after a key moved from one sibling to another
+	// through their parent.
+	Borrow(from, to *Node)
+	// Save is called after the tree was saved to path, successfully or not.
+	Save(path string, bytes int, elapsed time.Duration, err error)
+}
+
+// NopTracer implements Tracer with callbacks that do nothing. Embed it to
+// implement only some callbacks.
+type NopTracer struct{}
+
+// OpStart implements Tracer.
+func (NopTracer) OpStart(op string, key int) func() { return nil }
+
+// Visit implements Tracer.
+func (NopTracer) Visit(node *Node) {}
+
+// Split implements Tracer.
+func (NopTracer) Split(node, sibling *Node) {}
+
+// Merge implements Tracer.
+func (NopTracer) Merge(node, sibling *Node) {}
+
+// Borrow implements Tracer.
+func (NopTracer) Borrow(from, to *Node) {}
+
+// Save implements Tracer.
+func (NopTracer) Save(path string, bytes int, elapsed time.Duration, err error) {}
+
+// traceOp reports the start of an operation and returns the function to call
+// when it ends, or nil.
+func (t *Tree) traceOp(op string, key int) func() {
+	if t.Tracer == nil {
+		return nil
+	}
+	return t.Tracer.OpStart(op, key)
+}
+
+// visit reports a node examined during a descent.
+func (t *Tree) visit(node *Node) {
+	if t.Tracer != nil {
+		t.Tracer.Visit(node)
+	}
+}
@@ -3,17 +3,42 @@ package tree
 import (
 	"elastic-btree/pkg/logger" // Import the custom logger
 	"sync"
+	"time"
 )
 
 // Tree represents the Elastic B-Tree.
 type Tree struct {
-	Root       *Node              `json:"root"`   // Root node of the tree
-	Degree     int                `json:"degree"` // Minimum degree of the tree
-	Size       int                `json:"size"`   // Total number of keys in the tree
-	Height     int                `json:"height"` // Height of the tree
-	Lock       sync.RWMutex       `json:"-"`      // Mutex for concurrent access
-	Logger     *logger.Logger     `json:"-"`      // Custom logger for debugging
-	Comparator func(a, b int) int `json:"-"`      // Custom key comparator (default: ascending order)
+	Root            *Node                            `json:"root"`                   // Root node of the tree
+	Degree          int                              `json:"degree"`                 // Minimum degree of the tree
+	Size            int                              `json:"size"`                   // Total number of keys in the tree
+	Height          int                              `json:"height"`                 // Height of the tree
+	Lock            sync.RWMutex                     `json:"-"`                      // Mutex for concurrent access
+	Logger          *logger.Logger                   `json:"-"`                      // Custom logger for debugging
+	Comparator      func(a, b int) int               `json:"-"`                      // Custom key comparator (default: ascending order)
+	ComparatorName  string                           `json:"comparator,omitempty"`   // Registered name of Comparator, saved with the tree (see SetComparator)
+	DebugComparator bool                             `json:"-"`                      // Check Comparator is a total order on sampled keys before each insert
+	Version         uint64                           `json:"version"`                // Incremented by every change; reported in ChangeEvents
+	Expiry          map[int]int64                    `json:"expiry,omitempty"`       // Expiry times (Unix nanoseconds) of keys inserted with a TTL
+	Clock           func() time.Time                 `json:"-"`                      // Time source for TTL expiry (default: time.Now)
+	MaxEntries      int                              `json:"maxEntries,omitempty"`   // Evict once Size exceeds this (0: unbounded)
+	MaxBytes        int64                            `json:"maxBytes,omitempty"`     // Evict once the estimated entry size exceeds this (0: unbounded)
+	Eviction        EvictionPolicy                   `json:"eviction,omitempty"`     // Which entry to evict first (default: EvictLRU)
+	OnEvict         func(key int, value interface{}) `json:"-"`                      // Called under the write lock for every evicted entry
+	Evictions       uint64                           `json:"evictions,omitempty"`    // Entries evicted so far
+	EvictedBytes    uint64                           `json:"evictedBytes,omitempty"` // Estimated bytes evicted so far
+	Indexes         map[string]*Index                `json:"indexes,omitempty"`      // Secondary indexes by name (see AddIndex)
+	Sizing          SizingPolicy                     `json:"-"`                      // Capacity of leaves created by splits (default: FixedSizing)
+	Tracer          Tracer                           `json:"-"`                      // Receives operation and rebalancing callbacks (default: none)
+	Invariants      InvariantMode                    `json:"-"`                      // How much structure to check after changes (default: InvariantsLocal)
+
+	watchers      map[*Watcher]struct{} // Subscribers registered by Watch
+	hooks         []func(ChangeEvent)   // Called for every change; registered by OnChange
+	tracker       *evictionTracker      // Eviction order when MaxEntries or MaxBytes is set
+	comparatorErr error                 // First violation found by DebugComparator
+	splits        uint64                // Node splits, reported by Stats
+	merges        uint64                // Node merges, reported by Stats
+	borrows       uint64                // Keys borrowed from siblings, reported by Stats
+	metrics       *treeMetrics          // Set by Instrument
 }
 
 // NewTree creates a new Elastic B-Tree with the given degree and logger.
@@ -26,16 +51,123 @@ func NewTree(degree int, logger *logger.Logger) *Tree {
 		Root:       nil,
 		Size:       0,
 		Height:     0,
-		Comparator: func(a, b int) int { return a - b }, // Default comparator
+		Comparator: ascending, // Default comparator
 		Logger:     logger,
 	}
 }
 
-// Insert inserts a key into the tree.
+// Insert inserts a key into the tree. With DebugComparator set, a key that
+// exposes an inconsistent comparator is rejected; see ComparatorErr, or use
+// InsertChecked to learn about it.
 func (t *Tree) Insert(key int, value interface{}) {
+	t.InsertChecked(key, value)
+}
+
+// InsertChecked is Insert returning the *ComparatorError that rejected the key,
+// if any.
+func (t *Tree) InsertChecked(key int, value interface{}) error {
 	t.Lock.Lock()
 	defer t.Lock.Unlock()
+	if end := t.traceOp(OpInsert, key); end != nil {
+		defer end()
+	}
+	if t.metrics != nil {
+		defer t.metrics.observe(metricInsert, time.Now())
+	}
+
+	if err := t.checkInsertOrder(key); err != nil {
+		return err
+	}
+	t.insert(key, value)
+	delete(t.Expiry, key)
+	t.indexAdd(key, value)
+	t.notify(OpInsert, key, nil, value)
+	t.track(key, value)
+	return nil
+}
+
+// Put inserts a key or, if it already exists, replaces its value in place and
+// clears any TTL. It returns the previous value and whether the key was present.
+// A key rejected by DebugComparator is reported as not present; use PutChecked
+// to tell the two apart.
+func (t *Tree) Put(key int, value interface{}) (interface{}, bool) {
+	old, replaced, _ := t.PutChecked(key, value)
+	return old, replaced
+}
+
+// PutChecked is Put also returning the *ComparatorError that rejected the key,
+// if any.
+func (t *Tree) PutChecked(key int, value interface{}) (interface{}, bool, error) {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+	if end := t.traceOp(OpInsert, key); end != nil {
+		defer end()
+	}
+	if t.metrics != nil {
+		defer t.metrics.observe(metricInsert, time.Now())
+	}
+
+	return t.put(key, value)
+}
+
+// put implements PutChecked. The caller must hold the write lock.
+func (t *Tree) put(key int, value interface{}) (interface{}, bool, error) {
+	if err := t.checkInsertOrder(key); err != nil {
+		return nil, false, err
+	}
+	start := t.opStart()
+	delete(t.Expiry, key)
+	if node, i := t.findKey(t.Root, key); node != nil {
+		old := node.Values[i]
+		node.Values[i] = value
+		node.updates++
+		t.logOp(OpUpdate, key, node, start)
+		t.indexRemove(key, old)
+		t.indexAdd(key, value)
+		t.notify(OpUpdate, key, old, value)
+		t.trackUpdate(key, old, value)
+		return old, true, nil
+	}
+	t.insert(key, value)
+	t.indexAdd(key, value)
+	t.notify(OpInsert, key, nil, value)
+	t.track(key, value)
+	return nil, false, nil
+}
+
+// PutIf is Put applied only when the key's presence matches exists: with
+// exists false it only inserts a new key, with exists true it only replaces an
+// existing one. The check and the write happen under one lock, and an expired
+// key counts as absent. It returns the previous value, whether the value was
+// stored and, like PutChecked, the *ComparatorError that rejected the key.
+func (t *Tree) PutIf(key int, value interface{}, exists bool) (interface{}, bool, error) {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+	if end := t.traceOp(OpInsert, key); end != nil {
+		defer end()
+	}
+	if t.metrics != nil {
+		defer t.metrics.observe(metricInsert, time.Now())
+	}
 
+	node, _ := t.findKey(t.Root, key)
+	if node != nil && t.expired(key) {
+		t.remove(key)
+		node = nil
+	}
+	if (node != nil) != exists {
+		return nil, false, nil
+	}
+	old, _, err := t.put(key, value)
+	if err != nil {
+		return nil, false, err
+	}
+	return old, true, nil
+}
+
+// insert adds a key to the tree. The caller must hold the write lock.
+func (t *Tree) insert(key int, value interface{}) {
+	start := t.opStart()
 	if t.Root == nil {
 		t.Root = &Node{
 			Keys:     []int{key},
@@ -48,15 +180,20 @@ func (t *Tree) Insert(key int, value interface{}) {
 		}
 		t.Size++
 		t.Height = 1
-		t.Logger.Infof("Insert: created new root with key: %d", key)
+		if t.Logger.Enabled(logger.Debug) {
+			t.Logger.Debugf("Insert: created new root with key: %d", key)
+		}
+		t.logOp(OpInsert, key, t.Root, start)
 		return
 	}
 
 	// Check invariants before insertion.
 	t.checkInvariants(t.Root)
-	t.Logger.Infof("Insert: inserting key %d", key)
+	if t.Logger.Enabled(logger.Debug) {
+		t.Logger.Debugf("Insert: inserting key %d", key)
+	}
 
-	if t.Root.Size == t.Root.MaxKeys {
+	if t.Root.Size >= t.Root.MaxKeys {
 		// Split the root if it's full.
 		newRoot := &Node{
 			Keys:     []int{},
@@ -71,19 +208,23 @@ func (t *Tree) Insert(key int, value interface{}) {
 		t.splitChild(newRoot, 0)
 		t.Root = newRoot
 		t.Height++
-		t.Logger.Infof("Insert: root split; new root keys: %v", newRoot.Keys)
+		if t.Logger.Enabled(logger.Debug) {
+			t.Logger.Debugf("Insert: root split; new root keys: %v", newRoot.Keys)
+		}
 	}
 
-	t.insertNonFull(t.Root, key, value)
+	leaf := t.insertNonFull(t.Root, key, value)
 	t.Size++
 
 	// Check invariants after insertion.
 	t.checkInvariants(t.Root)
-	t.Logger.Infof("Insert: finished inserting key %d", key)
+	t.logOp(OpInsert, key, leaf, start)
 }
 
-// insertNonFull inserts a key into a non-full node.
-func (t *Tree) insertNonFull(node *Node, key int, value interface{}) {
+// insertNonFull inserts a key into a non-full node and returns the leaf that
+// received it.
+func (t *Tree) insertNonFull(node *Node, key int, value interface{}) *Node {
+	t.visit(node)
 	i := node.Size - 1
 	if node.IsLeaf {
 		// Insert into a leaf node
@@ -93,63 +234,81 @@ func (t *Tree) insertNonFull(node *Node, key int, value interface{}) {
 		node.Keys = append(node.Keys[:i+1], append([]int{key}, node.Keys[i+1:]...)...)
 		node.Values = append(node.Values[:i+1], append([]interface{}{value}, node.Values[i+1:]...)...)
 		node.Size++
+		node.inserts++
+		if i+1 == node.Size-1 {
+			node.appends++
+		}
+		return node
 	} else {
 		// Insert into an internal node
 		for i >= 0 && t.Comparator(node.Keys[i], key) > 0 {
 			i--
 		}
 		i++
-		if node.Children[i].Size == node.Children[i].MaxKeys {
+		if node.Children[i].Size >= node.Children[i].MaxKeys {
 			// Split the child if it's full
 			t.splitChild(node, i)
 			if t.Comparator(node.Keys[i], key) < 0 {
 				i++
 			}
 		}
-		t.insertNonFull(node.Children[i], key, value)
+		return t.insertNonFull(node.Children[i], key, value)
 	}
 }
 
 // splitChild splits a full child of a node.
 func (t *Tree) splitChild(parent *Node, index int) {
     child := parent.Children[index]
-    t.Logger.Infof("splitChild: splitting child at index %d with keys: %v", index, child.Keys)
+    if t.Logger.Enabled(logger.Debug) {
+        t.Logger.Debugf("splitChild: splitting child at index %d with keys: %v", index, child.Keys)
+    }
     // Check invariant before split.
     t.checkInvariants(child)
     t.normalizeChildren(parent)
+    t.splits++
+
+    // Split around the median; node capacities may differ from the degree.
+    mid := child.Size / 2
+    medianKey := child.Keys[mid]
+    medianValue := child.Values[mid]
 
-    medianKey := child.Keys[t.Degree-1]
-    medianValue := child.Values[t.Degree-1]
+    capacity := child.MaxKeys
+    if child.IsLeaf {
+        capacity = t.leafCapacity(child, mid, child.Size-mid-1)
+    }
 
     newChild := &Node{
-        Keys:     make([]int, t.Degree-1),
-        Values:   make([]interface{}, t.Degree-1),
+        Keys:     make([]int, child.Size-mid-1),
+        Values:   make([]interface{}, child.Size-mid-1),
         Children: []*Node{},
         IsLeaf:   child.IsLeaf,
-        Size:     t.Degree - 1,
-        MaxKeys:  2*t.Degree - 1,
-        MinKeys:  t.Degree - 1,
+        Size:     child.Size - mid - 1,
+        MaxKeys:  capacity,
+        MinKeys:  t.minKeysFor(capacity),
         Parent:   parent,
     }
 
     // Copy second half of keys/values to newChild.
-    copy(newChild.Keys, child.Keys[t.Degree:])
-    copy(newChild.Values, child.Values[t.Degree:])
+    copy(newChild.Keys, child.Keys[mid+1:])
+    copy(newChild.Values, child.Values[mid+1:])
     if !child.IsLeaf {
         // Copy the second half of children to the new child
-        newChild.Children = append(newChild.Children, child.Children[t.Degree:]...)
+        newChild.Children = append(newChild.Children, child.Children[mid+1:]...)
         for _, c := range newChild.Children {
             c.Parent = newChild
         }
     }
 
     // Update the original child
-    child.Keys = child.Keys[:t.Degree-1]
-    child.Values = child.Values[:t.Degree-1]
-    child.Size = t.Degree - 1
+    child.Keys = child.Keys[:mid]
+    child.Values = child.Values[:mid]
+    child.Size = mid
+    child.MaxKeys = capacity
+    child.MinKeys = t.minKeysFor(capacity)
+    child.inserts, child.appends, child.updates = 0, 0, 0
     if !child.IsLeaf {
-        // Retain t.Degree children for non-leaf nodes
-        child.Children = child.Children[:t.Degree]
+        // Retain mid+1 children for non-leaf nodes
+        child.Children = child.Children[:mid+1]
     }
 
     // Insert the median key/value into the parent and attach the new child.
@@ -159,145 +318,206 @@ func (t *Tree) splitChild(parent *Node, index int) {
     parent.Size = len(parent.Keys)
 
     t.normalizeChildren(parent)
-    t.Logger.Infof("splitChild: after split, parent keys: %v, children count: %d", parent.Keys, len(parent.Children))
+    if t.Logger.Enabled(logger.Debug) {
+        t.Logger.Debugf("splitChild: after split, parent keys: %v, children count: %d", parent.Keys, len(parent.Children))
+    }
     // Check invariants after split.
     t.checkInvariants(child)
     t.checkInvariants(newChild)
     t.checkInvariants(parent)
+    if t.Tracer != nil {
+        t.Tracer.Split(child, newChild)
+    }
 }
 
-// Search searches for a key in the tree and returns its value (if found).
+// Search searches for a key in the tree and returns its value (if found). An
+// expired key is reported as missing and removed. A hit counts as an access
+// for LRU and LFU eviction.
 func (t *Tree) Search(key int) (interface{}, bool) {
 	t.Lock.RLock()
-	defer t.Lock.RUnlock()
-
-	return t.searchNode(t.Root, key)
-}
-
-// searchNode searches for a key in a subtree rooted at the given node.
-func (t *Tree) searchNode(node *Node, key int) (interface{}, bool) {
-	if node == nil {
-		return nil, false
+	if end := t.traceOp(OpSearch, key); end != nil {
+		defer end()
 	}
-
-	i := 0
-	for i < node.Size && t.Comparator(node.Keys[i], key) < 0 {
-		i++
+	if t.metrics != nil {
+		defer t.metrics.observe(metricSearch, time.Now())
 	}
-
-	if i < node.Size && t.Comparator(node.Keys[i], key) == 0 {
-		// Key found
-		return node.Values[i], true
+	start := t.opStart()
+	node, i := t.findKey(t.Root, key)
+	var value interface{}
+	found := node != nil
+	if found {
+		value = node.Values[i]
 	}
+	t.logOp(OpSearch, key, node, start)
+	expired := found && t.expired(key)
+	if found && !expired {
+		t.touch(key)
+	}
+	t.Lock.RUnlock()
 
-	if node.IsLeaf {
-		// Key not found
+	if expired {
+		t.expireKey(key)
 		return nil, false
 	}
+	return value, found
+}
 
-	// Search in the appropriate child
-	return t.searchNode(node.Children[i], key)
+// findKey returns the node holding key and the key's index within it, or nil
+// if the key is not in the subtree.
+func (t *Tree) findKey(node *Node, key int) (*Node, int) {
+	for node != nil {
+		t.visit(node)
+		i := 0
+		for i < node.Size && t.Comparator(node.Keys[i], key) < 0 {
+			i++
+		}
+		if i < node.Size && t.Comparator(node.Keys[i], key) == 0 {
+			return node, i
+		}
+		if node.IsLeaf {
+			return nil, 0
+		}
+		node = node.Children[i]
+	}
+	return nil, 0
 }
 
-// Delete deletes a key from the tree.
+// Delete deletes a key from the tree. Deleting a missing key does nothing.
 func (t *Tree) Delete(key int) {
+	t.Remove(key)
+}
+
+// Remove deletes a key if it is present. It returns the removed value and
+// whether the key was found.
+func (t *Tree) Remove(key int) (interface{}, bool) {
 	t.Lock.Lock()
 	defer t.Lock.Unlock()
+	if end := t.traceOp(OpDelete, key); end != nil {
+		defer end()
+	}
+	if t.metrics != nil {
+		defer t.metrics.observe(metricDelete, time.Now())
+	}
+
+	return t.remove(key)
+}
+
+// remove implements Remove. The caller must hold the write lock.
+func (t *Tree) remove(key int) (interface{}, bool) {
+	start := t.opStart()
+	node, i := t.findKey(t.Root, key)
+	if node == nil {
+		t.logOp(OpDelete, key, nil, start)
+		return nil, false
+	}
+	old := node.Values[i]
+	t.deleteKey(key)
+	t.logOp(OpDelete, key, node, start)
+	delete(t.Expiry, key)
+	t.untrack(key, old)
+	t.indexRemove(key, old)
+	t.notify(OpDelete, key, old, nil)
+	return old, true
+}
 
+// deleteKey removes a key from the tree and reports whether it was present.
+// The caller must hold the write lock.
+func (t *Tree) deleteKey(key int) bool {
 	if t.Root == nil {
-		return
+		return false
 	}
-	t.Logger.Infof("Delete: deleting key %d", key)
-	t.deleteNode(t.Root, key)
+	if t.Logger.Enabled(logger.Debug) {
+		t.Logger.Debugf("Delete: deleting key %d", key)
+	}
+	found := t.deleteNode(t.Root, key)
 	if t.Root.Size == 0 && !t.Root.IsLeaf {
 		t.Root = t.Root.Children[0]
+		t.Root.Parent = nil
 		t.Height--
-		t.Logger.Infof("Delete: root became empty, new root keys: %v", t.Root.Keys)
+		if t.Logger.Enabled(logger.Debug) {
+			t.Logger.Debugf("Delete: root became empty, new root keys: %v", t.Root.Keys)
+		}
+	}
+	if found {
+		t.Size--
 	}
-	t.Size--
 	t.checkInvariants(t.Root)
-	t.Logger.Infof("Delete: finished deleting key %d", key)
+	if t.Logger.Enabled(logger.Debug) {
+		t.Logger.Debugf("Delete: finished deleting key %d", key)
+	}
+	return found
 }
 
-// deleteNode deletes a key from a subtree rooted at the given node.
-func (t *Tree) deleteNode(node *Node, key int) {
+// deleteNode deletes a key from a subtree rooted at the given node. Deletion
+// is top-down: before descending into a child, fillChild makes sure it has a
+// key to spare, so removing a key from a leaf never leaves it underfilled.
+func (t *Tree) deleteNode(node *Node, key int) bool {
+	t.visit(node)
 	i := 0
 	for i < node.Size && t.Comparator(node.Keys[i], key) < 0 {
 		i++
 	}
 	if i < node.Size && t.Comparator(node.Keys[i], key) == 0 {
 		if node.IsLeaf {
-			t.Logger.Infof("deleteNode: deleting key %d from leaf %v", key, node.Keys)
+			if t.Logger.Enabled(logger.Debug) {
+				t.Logger.Debugf("deleteNode: deleting key %d from leaf %v", key, node.Keys)
+			}
 			node.Keys = append(node.Keys[:i], node.Keys[i+1:]...)
 			node.Values = append(node.Values[:i], node.Values[i+1:]...)
 			node.Size--
-			t.checkInvariants(node)
-			if node.Size < node.MinKeys {
-				t.balanceAfterDeletion(node)
-			}
-		} else {
-			t.Logger.Infof("deleteNode: deleting key %d from internal node %v", key, node.Keys)
-			t.deleteInternal(node, i)
+			return true
 		}
-	} else if !node.IsLeaf {
-		t.deleteNode(node.Children[i], key)
+		if t.Logger.Enabled(logger.Debug) {
+			t.Logger.Debugf("deleteNode: deleting key %d from internal node %v", key, node.Keys)
+		}
+		t.deleteInternal(node, i)
+		return true
+	}
+	if node.IsLeaf {
+		return false
 	}
+	i = t.fillChild(node, i)
+	found := t.deleteNode(node.Children[i], key)
 	t.checkInvariants(node)
+	return found
 }
 
-// deleteInternal deletes a key from an internal node.
+// deleteInternal deletes the key at index from an internal node.
 func (t *Tree) deleteInternal(node *Node, index int) {
 	key := node.Keys[index]
 	leftChild := node.Children[index]
 	rightChild := node.Children[index+1]
 
-	t.Logger.Infof("deleteInternal: deleting key %d at index %d from node %v", key, index, node.Keys)
+	if t.Logger.Enabled(logger.Debug) {
+		t.Logger.Debugf("deleteInternal: deleting key %d at index %d from node %v", key, index, node.Keys)
+	}
 
-	// Case 1: Borrow predecessor.
+	// Case 1: Replace with the predecessor.
 	if leftChild.Size > leftChild.MinKeys {
-		predNode, predKey, predValue := t.getPredecessor(leftChild)
+		_, predKey, predValue := t.getPredecessor(leftChild)
 		node.Keys[index] = predKey
 		node.Values[index] = predValue
-		t.deleteNode(predNode, predKey)
+		t.deleteNode(leftChild, predKey)
 		t.checkInvariants(node)
 		return
 	}
 
-	// Case 2: Borrow successor.
+	// Case 2: Replace with the successor.
 	if rightChild.Size > rightChild.MinKeys {
-		succNode, succKey, succValue := t.getSuccessor(rightChild)
+		_, succKey, succValue := t.getSuccessor(rightChild)
 		node.Keys[index] = succKey
 		node.Values[index] = succValue
-		t.deleteNode(succNode, succKey)
+		t.deleteNode(rightChild, succKey)
 		t.checkInvariants(node)
 		return
 	}
 
-	t.Logger.Infof("deleteInternal: merging children for key %d at index %d", key, index)
-	// Case 3: Merge with sibling.
-	if node.Parent == nil || node.Parent.Size == 0 {
-		t.mergeChildren(node, index)
-		t.checkInvariants(node)
-		return
-	}
-	parent := node.Parent
-	childIndex := -1
-	for i, child := range parent.Children {
-		if child == node {
-			childIndex = i
-			break
-		}
-	}
-	if childIndex < 0 || childIndex >= len(parent.Children) {
-		t.Logger.Panicf("deleteInternal: node not found in parent's children")
-	}
-	if childIndex > 0 {
-		t.mergeWithLeftSibling(parent, childIndex-1)
-		t.deleteNode(parent.Children[childIndex-1], key)
-	} else {
-		t.mergeWithRightSibling(parent, childIndex)
-		t.deleteNode(parent.Children[childIndex], key)
+	// Case 3: Merge both children around the key and delete it from the result.
+	if t.Logger.Enabled(logger.Debug) {
+		t.Logger.Debugf("deleteInternal: merging children for key %d at index %d", key, index)
 	}
+	t.mergeWithRightSibling(node, index)
+	t.deleteNode(leftChild, key)
 	t.checkInvariants(node)
 }
 
@@ -364,70 +584,6 @@ func (t *Tree) rebuildParents(node *Node) {
     }
 }
 
-func (t *Tree) mergeChildren(node *Node, index int) {
-    if node == nil {
-        t.Logger.Panicf("mergeChildren: node is nil")
-    }
-    t.Logger.Infof("mergeChildren: BEFORE merge at index %d, node keys: %v, children count: %d",
-        index, node.Keys, len(node.Children))
-    if index < 0 || index >= len(node.Children)-1 {
-        t.Logger.Panicf("mergeChildren: invalid index %d (node.Children length %d)", index, len(node.Children))
-    }
-    leftChild := node.Children[index]
-    rightChild := node.Children[index+1]
-
-    t.Logger.Infof("mergeChildren: BEFORE merge, leftChild keys: %v, rightChild keys: %v",
-        leftChild.Keys, rightChild.Keys)
-
-    // Move the separator key from node to leftChild.
-    leftChild.Keys = append(leftChild.Keys, node.Keys[index])
-    leftChild.Values = append(leftChild.Values, node.Values[index])
-    leftChild.Size = len(leftChild.Keys)
-
-    // Merge rightChild's keys and values into leftChild.
-    leftChild.Keys = append(leftChild.Keys, rightChild.Keys...)
-    leftChild.Values = append(leftChild.Values, rightChild.Values...)
-    leftChild.Size = len(leftChild.Keys)
-
-    // Merge children pointers if not a leaf.
-    if !leftChild.IsLeaf {
-        leftChild.Children = append(leftChild.Children, rightChild.Children...)
-        for _, child := range rightChild.Children {
-            child.Parent = leftChild
-        }
-    }
-
-    // Remove the separator key and the pointer for rightChild from node.
-    node.Keys = append(node.Keys[:index], node.Keys[index+1:]...)
-    node.Values = append(node.Values[:index], node.Values[index+1:]...)
-    // Rebuild node.Children with a new allocation.
-    newChildren := make([]*Node, 0, len(node.Children)-1)
-    newChildren = append(newChildren, node.Children[:index+1]...)
-    newChildren = append(newChildren, node.Children[index+2:]...)
-    node.Children = newChildren
-    node.Size = len(node.Keys)
-
-    // Invariant: len(node.Children) should equal node.Size + 1.
-    expectedChildrenCount := node.Size + 1
-    if len(node.Children) != expectedChildrenCount {
-        t.Logger.Panicf("mergeChildren: invariant violation: node's children count %d != keys count %d + 1",
-            len(node.Children), node.Size)
-    }
-
-    t.Logger.Infof("mergeChildren: AFTER merge, node keys: %v, children count: %d",
-        node.Keys, len(node.Children))
-    t.checkInvariants(node)
-
-    // If node is the root and becomes empty, update the tree’s root.
-    if node.Size == 0 && node.Parent == nil {
-        t.Logger.Infof("mergeChildren: node is root and empty, replacing root with leftChild keys: %v",
-            leftChild.Keys)
-        t.Root = leftChild
-        leftChild.Parent = nil
-        t.Height--
-    }
-}
-
 // normalizeChildren rebuilds a node's children slice so that its length equals node.Size+1.
 func (t *Tree) normalizeChildren(node *Node) {
 	if node.IsLeaf {
@@ -0,0 +1,133 @@
+package tree
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultReapBatch is the number of expired keys RunReaper removes per lock
+// acquisition when given a batch size <= 0.
+const DefaultReapBatch = 1000
+
+// InsertWithTTL inserts or replaces a key that expires after ttl. Expired keys
+// are not returned by Search and are removed by ReapExpired; until then they
+// still count towards Size and appear in iteration. A ttl <= 0 stores the key
+// without an expiry. A key rejected by DebugComparator is not stored; use
+// InsertWithTTLChecked to learn about it.
+func (t *Tree) InsertWithTTL(key int, value interface{}, ttl time.Duration) {
+	t.InsertWithTTLChecked(key, value, ttl)
+}
+
+// InsertWithTTLChecked is InsertWithTTL returning, like PutChecked, the
+// previous value, whether the key was present and the *ComparatorError that
+// rejected the key, if any.
+func (t *Tree) InsertWithTTLChecked(key int, value interface{}, ttl time.Duration) (interface{}, bool, error) {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	old, replaced, err := t.put(key, value)
+	if err != nil {
+		return nil, false, err
+	}
+	// The key itself may have been evicted to make room.
+	if node, _ := t.findKey(t.Root, key); ttl > 0 && node != nil {
+		if t.Expiry == nil {
+			t.Expiry = make(map[int]int64)
+		}
+		t.Expiry[key] = t.now().Add(ttl).UnixNano()
+	}
+	return old, replaced, nil
+}
+
+// TTL returns the time left before a key expires. It returns false if the key
+// has no expiry or has already expired.
+func (t *Tree) TTL(key int) (time.Duration, bool) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	expiry, ok := t.Expiry[key]
+	if !ok {
+		return 0, false
+	}
+	left := time.Duration(expiry - t.now().UnixNano())
+	return left, left > 0
+}
+
+// ReapExpired removes up to limit expired keys (all of them if limit <= 0)
+// and returns how many were removed. Each removal rebalances the tree like
+// Delete and is reported to watchers as a delete.
+func (t *Tree) ReapExpired(limit int) int {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	now := t.now().UnixNano()
+	var expired []int
+	for key, expiry := range t.Expiry {
+		if expiry <= now {
+			expired = append(expired, key)
+			if limit > 0 && len(expired) == limit {
+				break
+			}
+		}
+	}
+	for _, key := range expired {
+		t.remove(key)
+	}
+	return len(expired)
+}
+
+// RunReaper removes expired keys every interval until ctx is cancelled. Keys
+// are removed in batches so readers are not blocked for long.
+func (t *Tree) RunReaper(ctx context.Context, interval time.Duration, batch int) {
+	if batch <= 0 {
+		batch = DefaultReapBatch
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		total := 0
+		for ctx.Err() == nil {
+			n := t.ReapExpired(batch)
+			total += n
+			if n < batch {
+				break
+			}
+		}
+		if total > 0 {
+			t.Logger.Infof("Reaper: removed %d expired keys", total)
+		}
+	}
+}
+
+// expired reports whether a key has an expiry that has passed. The caller must
+// hold the lock.
+func (t *Tree) expired(key int) bool {
+	expiry, ok := t.Expiry[key]
+	return ok && expiry <= t.now().UnixNano()
+}
+
+// expireKey removes a key found expired by a reader. The expiry is checked
+// again because the lock was released in between.
+func (t *Tree) expireKey(key int) {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	if t.expired(key) {
+		t.remove(key)
+	}
+}
+
+// now returns the current time from the tree's clock.
+func (t *Tree) now() time.Time {
+	if t.Clock == nil {
+		return time.Now()
+	}
+	return t.Clock()
+}
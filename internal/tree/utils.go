@@ -126,4 +126,91 @@ func (t *Tree) printNodeToString(buffer *bytes.Buffer, node *Node, level int) {
 	for _, child := range node.Children {
 		t.printNodeToString(buffer, child, level+1)
 	}
-}
\ No newline at end of file
+}
+
+// Clone returns a deep copy of the tree structure. Values are copied by reference.
+// The clone keeps the tree's settings and reports to the same metrics, but
+// change hooks and watchers stay with the original tree.
+func (t *Tree) Clone() *Tree {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	clone := &Tree{
+		Degree:         t.Degree,
+		Size:           t.Size,
+		Height:         t.Height,
+		Version:        t.Version,
+		Logger:         t.Logger,
+		Comparator:     t.Comparator,
+		ComparatorName: t.ComparatorName,
+		Clock:          t.Clock,
+		MaxEntries:     t.MaxEntries,
+		MaxBytes:       t.MaxBytes,
+		Eviction:       t.Eviction,
+		OnEvict:        t.OnEvict,
+		Evictions:      t.Evictions,
+		EvictedBytes:   t.EvictedBytes,
+		Sizing:         t.Sizing,
+		Tracer:         t.Tracer,
+		Invariants:     t.Invariants,
+
+		DebugComparator: t.DebugComparator,
+		metrics:         t.metrics,
+	}
+	if t.Expiry != nil {
+		clone.Expiry = make(map[int]int64, len(t.Expiry))
+		for key, expiry := range t.Expiry {
+			clone.Expiry[key] = expiry
+		}
+	}
+	if t.Indexes != nil {
+		clone.Indexes = make(map[string]*Index, len(t.Indexes))
+		for name, index := range t.Indexes {
+			clone.Indexes[name] = &Index{Tree: index.Tree.Clone(), Stale: index.Stale, keyFunc: index.keyFunc}
+		}
+	}
+	clone.Root = cloneNode(t.Root, nil)
+	return clone
+}
+
+// cloneNode recursively copies a subtree, attaching it to the given parent.
+func cloneNode(node *Node, parent *Node) *Node {
+	if node == nil {
+		return nil
+	}
+
+	copied := &Node{
+		Keys:     append([]int{}, node.Keys...),
+		Values:   append([]interface{}{}, node.Values...),
+		Children: make([]*Node, 0, len(node.Children)),
+		IsLeaf:   node.IsLeaf,
+		Parent:   parent,
+		Size:     node.Size,
+		MaxKeys:  node.MaxKeys,
+		MinKeys:  node.MinKeys,
+	}
+	for _, child := range node.Children {
+		copied.Children = append(copied.Children, cloneNode(child, copied))
+	}
+	return copied
+}
+
+// Restore replaces the contents of the tree with those of src under the write
+// lock, so readers see either the old or the new contents. The logger is kept.
+func (t *Tree) Restore(src *Tree) {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	t.Root = src.Root
+	t.Degree = src.Degree
+	t.Size = src.Size
+	t.Height = src.Height
+	t.Expiry = src.Expiry
+	t.tracker = nil // Rebuilt from the new contents on the next write
+	if src.Comparator != nil {
+		t.Comparator = src.Comparator
+		t.ComparatorName = src.ComparatorName
+	}
+	t.rebuildIndexes() // The tree keeps its own index definitions
+	t.notify(OpReset, 0, nil, nil)
+}
@@ -0,0 +1,127 @@
+package tree
+
+import "errors"
+
+// Change event operations.
+const (
+	OpInsert = "insert" // A key was added
+	OpUpdate = "update" // An existing key's value was replaced
+	OpDelete = "delete" // A key was removed
+	OpReset  = "reset"  // The whole tree was replaced (Restore); sent to every watcher
+)
+
+// DefaultWatchBuffer is the event buffer used when Watch is given a size <= 0.
+const DefaultWatchBuffer = 256
+
+// ErrWatchOverflow is reported by a Watcher whose buffer filled up because
+// events were not consumed fast enough. Its channel is closed and the caller
+// should resubscribe and resynchronise.
+var ErrWatchOverflow = errors.New("watch buffer overflow")
+
+// ChangeEvent describes one logical change made by Insert, Put, Delete,
+// Remove, BulkLoad or Restore, or a delete by eviction or TTL expiry.
+// Internal rebalancing never produces events.
+type ChangeEvent struct {
+	Op       string      `json:"op"`
+	Key      int         `json:"key"`
+	OldValue interface{} `json:"oldValue,omitempty"`
+	NewValue interface{} `json:"newValue,omitempty"`
+	Version  uint64      `json:"version"` // Tree version after the change
+}
+
+// Watcher delivers change events for keys in [from, to] on its channel C.
+type Watcher struct {
+	C <-chan ChangeEvent
+
+	tree     *Tree
+	from, to int
+	all      bool // Receive events for every key, ignoring from and to
+	ch       chan ChangeEvent
+	err      error
+}
+
+// Watch subscribes to changes of keys in [from, to], compared using the
+// tree's comparator. Events are buffered up to buffer entries; a watcher that
+// falls further behind is closed with ErrWatchOverflow rather than stalling
+// writers.
+func (t *Tree) Watch(from, to int, buffer int) *Watcher {
+	return t.addWatcher(&Watcher{tree: t, from: from, to: to}, buffer)
+}
+
+// WatchAll subscribes to changes of every key. It behaves like Watch otherwise.
+func (t *Tree) WatchAll(buffer int) *Watcher {
+	return t.addWatcher(&Watcher{tree: t, all: true}, buffer)
+}
+
+// addWatcher creates the watcher's channel and registers it.
+func (t *Tree) addWatcher(w *Watcher, buffer int) *Watcher {
+	if buffer <= 0 {
+		buffer = DefaultWatchBuffer
+	}
+	w.ch = make(chan ChangeEvent, buffer)
+	w.C = w.ch
+
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+	if t.watchers == nil {
+		t.watchers = make(map[*Watcher]struct{})
+	}
+	t.watchers[w] = struct{}{}
+	return w
+}
+
+// OnChange registers fn to be called with every change event, including the
+// deletes made by eviction and TTL expiry. Unlike a Watcher it never drops
+// events: fn runs synchronously under the write lock, so it must be quick and
+// must not call back into the tree. Hooks are not copied by Clone.
+func (t *Tree) OnChange(fn func(ChangeEvent)) {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+	t.hooks = append(t.hooks, fn)
+}
+
+// Close unsubscribes the watcher and closes its channel.
+func (w *Watcher) Close() {
+	w.tree.Lock.Lock()
+	defer w.tree.Lock.Unlock()
+
+	if _, ok := w.tree.watchers[w]; ok {
+		delete(w.tree.watchers, w)
+		close(w.ch)
+	}
+}
+
+// Err returns ErrWatchOverflow if the watcher was closed because it fell
+// behind, or nil otherwise. It is only meaningful after C has been closed.
+func (w *Watcher) Err() error {
+	w.tree.Lock.RLock()
+	defer w.tree.Lock.RUnlock()
+	return w.err
+}
+
+// notify advances the tree version and delivers an event to the change hooks
+// and interested watchers. The caller must hold the write lock.
+func (t *Tree) notify(op string, key int, oldValue, newValue interface{}) {
+	t.Version++
+	if len(t.hooks) == 0 && len(t.watchers) == 0 {
+		return
+	}
+
+	event := ChangeEvent{Op: op, Key: key, OldValue: oldValue, NewValue: newValue, Version: t.Version}
+	for _, hook := range t.hooks {
+		hook(event)
+	}
+	for w := range t.watchers {
+		if op != OpReset && !w.all && (t.Comparator(key, w.from) < 0 || t.Comparator(key, w.to) > 0) {
+			continue
+		}
+		select {
+		case w.ch <- event:
+		default:
+			w.err = ErrWatchOverflow
+			delete(t.watchers, w)
+			close(w.ch)
+			t.Logger.Warnf("Watch: dropped a watcher after buffer overflow")
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// pkg/client/client.go
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Options configures a Client. Zero values select the defaults.
+type Options struct {
+	MaxConns   int           // Maximum pooled connections to the server (default 16)
+	Timeout    time.Duration // Timeout for a single attempt (default 10s)
+	MaxRetries int           // Retries after a failed attempt (default 3, negative disables)
+	Backoff    time.Duration // Delay before the first retry, doubled each time (default 50ms)
+	MaxBackoff time.Duration // Upper bound on the retry delay (default 2s)
+	PageSize   int           // Pairs fetched per request by Ascend (default 1000)
+}
+
+// Pair is a key-value pair stored in the tree.
+type Pair struct {
+	Key   int         `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// Op is a single operation in a batch. Kind is one of OpGet, OpPut or OpDelete.
+type Op struct {
+	Kind  string      `json:"op"`
+	Key   int         `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Batch operation kinds.
+const (
+	OpGet    = "get"
+	OpPut    = "put"
+	OpDelete = "delete"
+)
+
+// Result is the outcome of one batch operation. Found reports whether the
+// key existed before the operation; Error is set if the server rejected a put.
+type Result struct {
+	Kind  string      `json:"op"`
+	Key   int         `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+	Found bool        `json:"found"`
+	Error string      `json:"error,omitempty"`
+}
+
+// Stats describes the served tree.
+type Stats struct {
+	Size         int    `json:"size"`
+	Height       int    `json:"height"`
+	Degree       int    `json:"degree"`
+	Evictions    uint64 `json:"evictions"`    // Entries evicted by a size-bounded tree
+	EvictedBytes uint64 `json:"evictedBytes"` // Estimated bytes evicted
+}
+
+// Event is a change to a watched key. Op is "insert", "update", "delete" or
+// "reset"; a reset means the server's tree was replaced wholesale.
+type Event struct {
+	Op       string      `json:"op"`
+	Key      int         `json:"key"`
+	OldValue interface{} `json:"oldValue,omitempty"`
+	NewValue interface{} `json:"newValue,omitempty"`
+	Version  uint64      `json:"version"`
+}
+
+// StatusError is returned when the server answers with an unexpected status.
+type StatusError struct {
+	Code    int    // HTTP status code
+	Message string // Error message from the server, if any
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.Code, e.Message)
+}
+
+// Client talks to a tree served by the HTTP server. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	opts    Options
+}
+
+// New creates a Client for the server at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts Options) *Client {
+	if opts.MaxConns <= 0 {
+		opts.MaxConns = 16
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 50 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 2 * time.Second
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 1000
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.MaxConns
+	transport.MaxIdleConnsPerHost = opts.MaxConns
+	transport.MaxConnsPerHost = opts.MaxConns
+
+	return &Client{
+		baseURL: baseURL,
+		http:    &http.Client{Transport: transport},
+		opts:    opts,
+	}
+}
+
+// Close releases pooled connections.
+func (c *Client) Close() {
+	c.http.CloseIdleConnections()
+}
+
+// Insert stores a value under key, replacing any existing value.
+func (c *Client) Insert(ctx context.Context, key int, value interface{}) error {
+	body := map[string]interface{}{"value": value}
+	return c.do(ctx, http.MethodPut, keyPath(key), body, nil, http.StatusOK, http.StatusCreated)
+}
+
+// Search returns the value stored under key and whether it exists.
+func (c *Client) Search(ctx context.Context, key int) (interface{}, bool, error) {
+	var pair Pair
+	err := c.do(ctx, http.MethodGet, keyPath(key), nil, &pair, http.StatusOK)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return pair.Value, true, nil
+}
+
+// Delete removes key and reports whether it existed.
+func (c *Client) Delete(ctx context.Context, key int) (bool, error) {
+	err := c.do(ctx, http.MethodDelete, keyPath(key), nil, nil, http.StatusNoContent)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Range returns up to limit pairs with from <= key <= to in key order.
+func (c *Client) Range(ctx context.Context, from, to, limit int) ([]Pair, error) {
+	query := url.Values{}
+	query.Set("from", strconv.Itoa(from))
+	query.Set("to", strconv.Itoa(to))
+	query.Set("limit", strconv.Itoa(limit))
+
+	var pairs []Pair
+	if err := c.do(ctx, http.MethodGet, "/range?"+query.Encode(), nil, &pairs, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// Ascend calls fn for every pair with from <= key <= to in key order until fn
+// returns false, fetching PageSize pairs per request. Pairs written while
+// iterating may or may not be observed.
+func (c *Client) Ascend(ctx context.Context, from, to int, fn func(key int, value interface{}) bool) error {
+	for from <= to {
+		pairs, err := c.Range(ctx, from, to, c.opts.PageSize)
+		if err != nil {
+			return err
+		}
+		for _, p := range pairs {
+			if !fn(p.Key, p.Value) {
+				return nil
+			}
+		}
+		if len(pairs) < c.opts.PageSize {
+			return nil
+		}
+		last := pairs[len(pairs)-1].Key
+		if last == math.MaxInt {
+			return nil
+		}
+		from = last + 1
+	}
+	return nil
+}
+
+// Watch streams change events for keys with from <= key <= to, calling fn for
+// each until fn returns false, ctx is done or the stream ends. Pass
+// math.MinInt or math.MaxInt for an open bound. Watch does not retry; if the
+// server drops the watcher for falling behind, the returned error says so.
+func (c *Client) Watch(ctx context.Context, from, to int, fn func(Event) bool) error {
+	query := url.Values{}
+	if from != math.MinInt {
+		query.Set("from", strconv.Itoa(from))
+	}
+	if to != math.MaxInt {
+		query.Set("to", strconv.Itoa(to))
+	}
+
+	// The stream is long-lived, so the per-attempt timeout does not apply.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/watch?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		return &StatusError{Code: resp.StatusCode, Message: errBody.Error}
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var line struct {
+			Event
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&line); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode event: %v", err)
+		}
+		if line.Error != "" {
+			return fmt.Errorf("watch ended by server: %s", line.Error)
+		}
+		if !fn(line.Event) {
+			return nil
+		}
+	}
+}
+
+// Batch applies ops in order on the server and returns one result per op.
+// Batches are not atomic: a retried batch may report different Found values
+// for operations that were already applied, though the final state is the same.
+func (c *Client) Batch(ctx context.Context, ops []Op) ([]Result, error) {
+	var results []Result
+	if err := c.do(ctx, http.MethodPost, "/batch", ops, &results, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Stats returns the size, height and degree of the served tree.
+func (c *Client) Stats(ctx context.Context) (Stats, error) {
+	var stats Stats
+	err := c.do(ctx, http.MethodGet, "/stats", nil, &stats, http.StatusOK)
+	return stats, err
+}
+
+// do sends a request, retrying transport errors and 5xx/429 responses with
+// exponential backoff until the retries or ctx run out. The response body is
+// decoded into out when it is non-nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, expected ...int) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+	}
+
+	backoff := c.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, path, payload, out, expected)
+		if err == nil || !retryable(err) || attempt >= c.opts.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		// Full jitter keeps concurrent clients from retrying in lockstep.
+		delay := time.Duration(rand.Int63n(int64(backoff) + 1))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, c.opts.MaxBackoff)
+	}
+}
+
+// attempt performs a single HTTP round trip bounded by the per-attempt timeout.
+func (c *Client) attempt(ctx context.Context, method, path string, payload []byte, out interface{}, expected []int) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			if out == nil {
+				io.Copy(io.Discard, resp.Body)
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("failed to decode response: %v", err)
+			}
+			return nil
+		}
+	}
+
+	var errBody struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&errBody)
+	return &StatusError{Code: resp.StatusCode, Message: errBody.Error}
+}
+
+// retryable reports whether a failed attempt may succeed if repeated.
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 500 || statusErr.Code == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+func keyPath(key int) string {
+	return "/keys/" + strconv.Itoa(key)
+}
@@ -3,49 +3,340 @@ package config
 
 import (
 	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// KeepLimit is the MaxEntries or MaxBytes value that keeps the limit saved
+// with the tree. It is the default, so a tree's limits survive restarts.
+const KeepLimit = -1
+
 // Config holds the application configuration.
 type Config struct {
-	TreeDegree  int          // B-tree degree
-	LogLevel    logger.Level // Logging level (debug, info, warn, error)
-	StoragePath string       // Path to the storage file
+	TreeDegree     int           // B-tree degree
+	LogLevel       logger.Level  // Logging level (debug, info, warn, error)
+	LogFormat      string        // Log output format: text or json
+	StoragePath    string        // Path to the storage file
+	StorageBackend string        // Storage backend; only "file" is supported
+	Fsync          string        // When saves are flushed to disk: always or never
+	MaxEntries     int           // Evict entries beyond this count (0: no limit, KeepLimit: keep the tree's setting)
+	MaxBytes       int64         // Evict entries beyond this estimated size (0: no limit, KeepLimit: keep the tree's setting)
+	Eviction       string        // Eviction policy: lru, lfu or smallest (empty: keep the tree's setting)
+	Comparator     string        // Registered comparator name for new trees
+	Invariants     string        // Invariant checking: off, local or full
+	HTTPAddr       string        // Default listen address of serve
+	RESPAddr       string        // Default listen address of serve-resp
+	ReplicateAddr  string        // Default replication address of serve and serve-resp (empty disables)
+	Checkpoint     time.Duration // Default interval between saves of a served tree (0 disables)
+
+	File    string            // Config file that was read, if any
+	sources map[string]Source // Where each option's value came from
+	args    []string          // Flags Parse read, replayed by Reload
+}
+
+// Source says where an option's value came from.
+type Source string
+
+const (
+	FromDefault Source = "default"
+	FromFile    Source = "file"
+	FromEnv     Source = "env"
+	FromFlag    Source = "flag"
+)
+
+// Setting is an option's effective value, as reported by Settings.
+type Setting struct {
+	Name       string `json:"name"`       // Config file key
+	Flag       string `json:"flag"`       // Command-line flag, without dashes
+	Env        string `json:"env"`        // Environment variable
+	Value      string `json:"value"`      // Effective value
+	Source     Source `json:"source"`     // Where Value came from
+	Reloadable bool   `json:"reloadable"` // Whether a running server applies changes on reload
+}
+
+// option describes one setting and how to read it from each source.
+type option struct {
+	name string // Config file key
+	flag string // Command-line flag
+	env  string // Environment variable
+	help string
+	set  func(c *Config, s string) error // Parses and validates s
+	get  func(c *Config) string
+}
+
+// options lists every setting in the order config show prints them.
+var options = []option{
+	{"degree", "degree", "TREE_DEGREE", "B-tree degree of new trees (>= 2)",
+		func(c *Config, s string) error {
+			degree, err := strconv.Atoi(s)
+			if err != nil || degree < 2 {
+				return fmt.Errorf("must be an integer >= 2")
+			}
+			c.TreeDegree = degree
+			return nil
+		},
+		func(c *Config) string { return strconv.Itoa(c.TreeDegree) }},
+	{"logLevel", "log-level", "LOG_LEVEL", "logging level: debug, info, warn or error",
+		func(c *Config, s string) error {
+			level, err := logger.ParseLevel(s)
+			if err != nil {
+				return fmt.Errorf("must be debug, info, warn or error")
+			}
+			c.LogLevel = level
+			return nil
+		},
+		func(c *Config) string { return strings.ToLower(c.LogLevel.String()) }},
+	{"logFormat", "log-format", "LOG_FORMAT", "log output format: text or json",
+		oneOf(func(c *Config) *string { return &c.LogFormat }, "text", "json"),
+		func(c *Config) string { return c.LogFormat }},
+	{"storagePath", "storage-path", "STORAGE_PATH", "path to the storage file",
+		func(c *Config, s string) error {
+			if s == "" {
+				return fmt.Errorf("must not be empty")
+			}
+			c.StoragePath = s
+			return nil
+		},
+		func(c *Config) string { return c.StoragePath }},
+	{"storageBackend", "storage-backend", "STORAGE_BACKEND", "storage backend: file",
+		oneOf(func(c *Config) *string { return &c.StorageBackend }, "file"),
+		func(c *Config) string { return c.StorageBackend }},
+	{"fsync", "fsync", "FSYNC_POLICY", "flush saves to disk before reporting success: always or never",
+		oneOf(func(c *Config) *string { return &c.Fsync }, "always", "never"),
+		func(c *Config) string { return c.Fsync }},
+	{"comparator", "comparator", "TREE_COMPARATOR", "registered key order of new trees",
+		func(c *Config, s string) error {
+			if s == "" {
+				return fmt.Errorf("must not be empty")
+			}
+			c.Comparator = s
+			return nil
+		},
+		func(c *Config) string { return c.Comparator }},
+	{"invariants", "invariants", "INVARIANT_MODE", "invariant checking: off, local or full",
+		oneOf(func(c *Config) *string { return &c.Invariants }, "off", "local", "full"),
+		func(c *Config) string { return c.Invariants }},
+	{"maxEntries", "max-entries", "MAX_ENTRIES", "evict entries beyond this count (0 removes the limit, -1 keeps the tree's setting)",
+		func(c *Config, s string) error {
+			maxEntries, err := strconv.Atoi(s)
+			if err != nil || maxEntries < KeepLimit {
+				return fmt.Errorf("must be an integer >= -1")
+			}
+			c.MaxEntries = maxEntries
+			return nil
+		},
+		func(c *Config) string { return strconv.Itoa(c.MaxEntries) }},
+	{"maxBytes", "max-bytes", "MAX_BYTES", "evict entries beyond this estimated size (0 removes the limit, -1 keeps the tree's setting)",
+		func(c *Config, s string) error {
+			maxBytes, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || maxBytes < KeepLimit {
+				return fmt.Errorf("must be an integer >= -1")
+			}
+			c.MaxBytes = maxBytes
+			return nil
+		},
+		func(c *Config) string { return strconv.FormatInt(c.MaxBytes, 10) }},
+	{"eviction", "eviction", "EVICTION_POLICY", "eviction policy: lru, lfu or smallest (empty keeps the tree's setting)",
+		oneOf(func(c *Config) *string { return &c.Eviction }, "", "lru", "lfu", "smallest"),
+		func(c *Config) string { return c.Eviction }},
+	{"httpAddr", "http-addr", "HTTP_ADDR", "listen address of serve",
+		address(func(c *Config) *string { return &c.HTTPAddr }, false),
+		func(c *Config) string { return c.HTTPAddr }},
+	{"respAddr", "resp-addr", "RESP_ADDR", "listen address of serve-resp",
+		address(func(c *Config) *string { return &c.RESPAddr }, false),
+		func(c *Config) string { return c.RESPAddr }},
+	{"replicateAddr", "replicate-addr", "REPLICATE_ADDR", "address to accept replication followers on (empty disables)",
+		address(func(c *Config) *string { return &c.ReplicateAddr }, true),
+		func(c *Config) string { return c.ReplicateAddr }},
+	{"checkpoint", "checkpoint", "CHECKPOINT_INTERVAL", "interval between saves of a served tree, e.g. 30s (0 disables)",
+		func(c *Config, s string) error {
+			checkpoint, err := time.ParseDuration(s)
+			if err != nil || checkpoint < 0 {
+				return fmt.Errorf("must be a duration >= 0, such as 30s")
+			}
+			c.Checkpoint = checkpoint
+			return nil
+		},
+		func(c *Config) string { return c.Checkpoint.String() }},
+}
+
+// oneOf returns a setter accepting only the given values.
+func oneOf(field func(c *Config) *string, values ...string) func(c *Config, s string) error {
+	return func(c *Config, s string) error {
+		for _, v := range values {
+			if s == v {
+				*field(c) = s
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+// address returns a setter accepting host:port addresses.
+func address(field func(c *Config) *string, optional bool) func(c *Config, s string) error {
+	return func(c *Config, s string) error {
+		if s == "" && optional {
+			*field(c) = s
+			return nil
+		}
+		if _, port, err := net.SplitHostPort(s); err != nil || port == "" {
+			return fmt.Errorf("must be a host:port address")
+		}
+		*field(c) = s
+		return nil
+	}
+}
+
+// defaults returns the configuration used when no source sets an option.
+func defaults() *Config {
+	return &Config{
+		TreeDegree:     3,
+		LogLevel:       logger.Info,
+		LogFormat:      "text",
+		StoragePath:    "data/tree.json",
+		StorageBackend: "file",
+		Fsync:          "never",
+		MaxEntries:     KeepLimit,
+		MaxBytes:       KeepLimit,
+		Comparator:     "ascending",
+		Invariants:     "local",
+		HTTPAddr:       ":8080",
+		RESPAddr:       ":6379",
+		Checkpoint:     30 * time.Second,
+		sources:        make(map[string]Source),
+	}
 }
 
-// Load loads the configuration from environment variables.
+// Load loads the configuration from the file named by CONFIG_FILE, if set,
+// and environment variables.
 func Load() (*Config, error) {
-	// Default values
-	cfg := &Config{
-		TreeDegree:  3,
-		LogLevel:    logger.Info,
-		StoragePath: "data/tree.json",
+	cfg, _, err := Parse(nil)
+	return cfg, err
+}
+
+// Parse builds the configuration from defaults, a JSON config file, the
+// environment and the leading flags in args, each overriding the one before.
+// The file is named by --config or CONFIG_FILE. Parsing stops at the first
+// non-flag argument; the remaining arguments are returned.
+func Parse(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("elastic-btree", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "JSON config file")
+	flags := make(map[string]string)
+	for _, opt := range options {
+		opt := opt
+		fs.Func(opt.flag, opt.help, func(s string) error {
+			flags[opt.name] = s
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := defaults()
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, opt := range options {
+		if value, ok := os.LookupEnv(opt.env); ok && value != "" {
+			if err := cfg.apply(opt, value, FromEnv); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for _, opt := range options {
+		if value, ok := flags[opt.name]; ok {
+			if err := cfg.apply(opt, value, FromFlag); err != nil {
+				return nil, nil, err
+			}
+		}
 	}
+	cfg.args = append([]string(nil), args[:len(args)-fs.NArg()]...)
+	return cfg, fs.Args(), nil
+}
+
+// loadFile applies the options set in a JSON config file. Values may be JSON
+// strings, numbers or booleans; unknown keys are rejected.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	c.File = path
 
-	// Load TreeDegree from environment
-	if degreeStr := os.Getenv("TREE_DEGREE"); degreeStr != "" {
-		degree, err := strconv.Atoi(degreeStr)
-		if err != nil || degree < 2 {
-			return nil, fmt.Errorf("invalid TREE_DEGREE: %s (must be >= 2)", degreeStr)
+	for _, opt := range options {
+		raw, ok := values[opt.name]
+		if !ok {
+			continue
+		}
+		delete(values, opt.name)
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw) // Numbers and booleans
+		}
+		if err := c.apply(opt, value, FromFile); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
 		}
-		cfg.TreeDegree = degree
 	}
+	for name := range values {
+		return fmt.Errorf("%s: unknown option %q", path, name)
+	}
+	return nil
+}
 
-	// Load LogLevel from environment
-	if logLevelStr := os.Getenv("LOG_LEVEL"); logLevelStr != "" {
-		logLevel, err := logger.ParseLevel(logLevelStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid LOG_LEVEL: %s", logLevelStr)
+// apply sets an option from a source.
+func (c *Config) apply(opt option, value string, source Source) error {
+	if err := opt.set(c, value); err != nil {
+		var from string
+		switch source {
+		case FromFlag:
+			from = "--" + opt.flag
+		case FromEnv:
+			from = opt.env
+		default:
+			from = opt.name
 		}
-		cfg.LogLevel = logLevel
+		return fmt.Errorf("invalid %s: %s (%v)", from, value, err)
 	}
+	c.sources[opt.name] = source
+	return nil
+}
 
-	// Load StoragePath from environment
-	if storagePath := os.Getenv("STORAGE_PATH"); storagePath != "" {
-		cfg.StoragePath = storagePath
+// Source returns where the named option (a config file key) got its value.
+func (c *Config) Source(name string) Source {
+	if source, ok := c.sources[name]; ok {
+		return source
 	}
+	return FromDefault
+}
 
-	return cfg, nil
+// Settings returns every option's effective value and source.
+func (c *Config) Settings() []Setting {
+	settings := make([]Setting, 0, len(options))
+	for _, opt := range options {
+		settings = append(settings, Setting{
+			Name:       opt.name,
+			Flag:       opt.flag,
+			Env:        opt.env,
+			Value:      opt.get(c),
+			Source:     c.Source(opt.name),
+			Reloadable: reloadable[opt.name],
+		})
+	}
+	return settings
 }
@@ -0,0 +1,141 @@
+// pkg/config/reload.go
+package config
+
+import (
+	"context"
+	"elastic-btree/pkg/logger"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reloadable lists the options a running server can change without a
+// restart.
+var reloadable = map[string]bool{
+	"logLevel":   true,
+	"invariants": true,
+	"checkpoint": true,
+	"maxEntries": true,
+	"maxBytes":   true,
+	"eviction":   true,
+}
+
+// Watcher reloads a configuration when its file changes or Reload is called.
+// A reload reads the file, environment and flags again with the usual
+// precedence. Invalid configurations are rejected as a whole; otherwise the
+// changed options that are reloadable are applied together, and changes to
+// any other option are logged and ignored until restart.
+type Watcher struct {
+	log *logger.Logger
+
+	mu       sync.Mutex
+	current  *Config
+	onChange []func(cfg *Config)
+	modTime  time.Time // Of the config file when it was last read
+	size     int64
+}
+
+// NewWatcher creates a Watcher starting from cfg, as returned by Parse.
+func NewWatcher(cfg *Config, log *logger.Logger) *Watcher {
+	w := &Watcher{log: log, current: cfg}
+	w.modTime, w.size = fileStamp(cfg.File)
+	return w
+}
+
+// Config returns the current configuration. It must not be modified.
+func (w *Watcher) Config() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// OnChange registers fn to be called with the new configuration after each
+// reload that changes a reloadable option. Calls are serialized.
+func (w *Watcher) OnChange(fn func(cfg *Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// Reload reads the configuration again and applies its reloadable changes.
+// It returns an error, leaving the configuration unchanged, if the new
+// configuration is invalid.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.modTime, w.size = fileStamp(w.current.File)
+	next, _, err := Parse(w.current.args)
+	if err != nil {
+		w.log.Errorf("Config reload rejected: %v", err)
+		return err
+	}
+
+	updated := *w.current
+	updated.sources = make(map[string]Source, len(w.current.sources))
+	for name, source := range w.current.sources {
+		updated.sources[name] = source
+	}
+	var changed []string
+	for _, opt := range options {
+		value := opt.get(next)
+		if value == opt.get(w.current) {
+			continue
+		}
+		if !reloadable[opt.name] {
+			w.log.Warnf("Config reload: %s cannot change at runtime; keeping %q until restart", opt.name, opt.get(w.current))
+			continue
+		}
+		opt.set(&updated, value) // Already validated by Parse
+		updated.sources[opt.name] = next.Source(opt.name)
+		changed = append(changed, opt.name)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	w.current = &updated
+	for _, fn := range w.onChange {
+		fn(w.current)
+	}
+	w.log.Infof("Config reloaded: %s", strings.Join(changed, ", "))
+	return nil
+}
+
+// Run checks the config file for changes every interval and reloads it when
+// it changed, until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			file, modTime, size := w.current.File, w.modTime, w.size
+			w.mu.Unlock()
+			if file == "" {
+				continue
+			}
+			if m, s := fileStamp(file); !m.Equal(modTime) || s != size {
+				w.Reload()
+			}
+		}
+	}
+}
+
+// fileStamp returns the modification time and size of path, or zero values
+// if it cannot be read.
+func fileStamp(path string) (time.Time, int64) {
+	if path == "" {
+		return time.Time{}, 0
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
@@ -0,0 +1,173 @@
+// pkg/keys/keys.go
+package keys
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Type codes written before each encoded element. Elements of different types
+// in the same position sort by these codes.
+const (
+	codeBytes  = 0x01
+	codeString = 0x02
+	codeInt    = 0x03
+	codeUint   = 0x04
+	codeTime   = 0x05
+	codeFalse  = 0x06
+	codeTrue   = 0x07
+)
+
+// ErrTruncated is returned by Decode for a key that ends mid-element.
+var ErrTruncated = errors.New("truncated tuple key")
+
+// Encode encodes a tuple such as (tenant, timestamp, id) into bytes whose
+// bytes.Compare order matches comparing the elements one by one. Supported
+// element types are []byte, string, signed and unsigned integers, time.Time
+// and bool. A tuple sorts before every longer tuple it is a prefix of, so
+// Encode(tenant) is a prefix of every key for that tenant.
+func Encode(parts ...interface{}) ([]byte, error) {
+	return Append(nil, parts...)
+}
+
+// Append is like Encode but appends to dst.
+func Append(dst []byte, parts ...interface{}) ([]byte, error) {
+	for i, part := range parts {
+		switch v := part.(type) {
+		case []byte:
+			dst = appendEscaped(append(dst, codeBytes), v)
+		case string:
+			dst = appendEscaped(append(dst, codeString), []byte(v))
+		case int:
+			dst = appendInt(dst, codeInt, int64(v))
+		case int8:
+			dst = appendInt(dst, codeInt, int64(v))
+		case int16:
+			dst = appendInt(dst, codeInt, int64(v))
+		case int32:
+			dst = appendInt(dst, codeInt, int64(v))
+		case int64:
+			dst = appendInt(dst, codeInt, v)
+		case uint:
+			dst = binary.BigEndian.AppendUint64(append(dst, codeUint), uint64(v))
+		case uint8:
+			dst = binary.BigEndian.AppendUint64(append(dst, codeUint), uint64(v))
+		case uint16:
+			dst = binary.BigEndian.AppendUint64(append(dst, codeUint), uint64(v))
+		case uint32:
+			dst = binary.BigEndian.AppendUint64(append(dst, codeUint), uint64(v))
+		case uint64:
+			dst = binary.BigEndian.AppendUint64(append(dst, codeUint), v)
+		case time.Time:
+			dst = appendInt(dst, codeTime, v.UnixNano())
+		case bool:
+			if v {
+				dst = append(dst, codeTrue)
+			} else {
+				dst = append(dst, codeFalse)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported tuple element %d of type %T", i, part)
+		}
+	}
+	return dst, nil
+}
+
+// MustEncode is like Encode but panics on unsupported element types. It is
+// meant for keys built from literals.
+func MustEncode(parts ...interface{}) []byte {
+	key, err := Encode(parts...)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Decode splits a key produced by Encode back into its elements. Integers
+// decode as int64 or uint64 and times as UTC time.Time values.
+func Decode(key []byte) ([]interface{}, error) {
+	var parts []interface{}
+	for len(key) > 0 {
+		code := key[0]
+		key = key[1:]
+		switch code {
+		case codeBytes, codeString:
+			value, rest, err := readEscaped(key)
+			if err != nil {
+				return nil, err
+			}
+			if code == codeString {
+				parts = append(parts, string(value))
+			} else {
+				parts = append(parts, value)
+			}
+			key = rest
+		case codeInt, codeUint, codeTime:
+			if len(key) < 8 {
+				return nil, ErrTruncated
+			}
+			n := binary.BigEndian.Uint64(key)
+			key = key[8:]
+			switch code {
+			case codeInt:
+				parts = append(parts, int64(n^(1<<63)))
+			case codeUint:
+				parts = append(parts, n)
+			default:
+				parts = append(parts, time.Unix(0, int64(n^(1<<63))).UTC())
+			}
+		case codeFalse:
+			parts = append(parts, false)
+		case codeTrue:
+			parts = append(parts, true)
+		default:
+			return nil, fmt.Errorf("unknown tuple type code 0x%02x", code)
+		}
+	}
+	return parts, nil
+}
+
+// appendInt writes a signed integer big-endian with the sign bit flipped, so
+// negative numbers sort before positive ones.
+func appendInt(dst []byte, code byte, v int64) []byte {
+	return binary.BigEndian.AppendUint64(append(dst, code), uint64(v)^(1<<63))
+}
+
+// appendEscaped writes b with 0x00 escaped as 0x00 0xFF and terminated by
+// 0x00 0x00, which sorts before any continuation.
+func appendEscaped(dst, b []byte) []byte {
+	for _, c := range b {
+		if c == 0x00 {
+			dst = append(dst, 0x00, 0xFF)
+		} else {
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, 0x00, 0x00)
+}
+
+// readEscaped reverses appendEscaped and returns the remaining input.
+func readEscaped(key []byte) ([]byte, []byte, error) {
+	value := []byte{}
+	for i := 0; i < len(key); i++ {
+		if key[i] != 0x00 {
+			value = append(value, key[i])
+			continue
+		}
+		if i+1 >= len(key) {
+			return nil, nil, ErrTruncated
+		}
+		switch key[i+1] {
+		case 0x00:
+			return value, key[i+2:], nil
+		case 0xFF:
+			value = append(value, 0x00)
+			i++
+		default:
+			return nil, nil, fmt.Errorf("invalid escape 0x00 0x%02x in tuple key", key[i+1])
+		}
+	}
+	return nil, nil, ErrTruncated
+}
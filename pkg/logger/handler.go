@@ -0,0 +1,175 @@
+// pkg/logger/handler.go
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewTextHandler writes records as lines in the standard library log format,
+// followed by fields as key=value pairs:
+//
+//	2024/01/02 15:04:05 tree.go:42: [INFO] inserted op=insert key=42
+func NewTextHandler(out io.Writer) Handler {
+	return &textHandler{out: out}
+}
+
+type textHandler struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (h *textHandler) Handle(r Record) error {
+	var b bytes.Buffer
+	b.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+	if caller := shortCaller(r.PC); caller != "" {
+		b.WriteString(caller)
+		b.WriteString(": ")
+	}
+	fmt.Fprintf(&b, "[%s] %s", r.Level, r.Message)
+	for _, f := range r.Fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		b.WriteString(textValue(f.Value))
+	}
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(b.Bytes())
+	return err
+}
+
+// textValue formats a field value, quoting it if it would be ambiguous.
+func textValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// NewJSONHandler writes each record as one JSON object per line, with the
+// keys time, level, caller and msg followed by the record's fields:
+//
+//	{"time":"2024-01-02T15:04:05.123Z","level":"INFO","caller":"tree.go:42","msg":"inserted","key":42}
+//
+// Values that cannot be encoded as JSON are written as strings; durations
+// are written as strings such as "1.5ms".
+func NewJSONHandler(out io.Writer) Handler {
+	return &jsonHandler{out: out}
+}
+
+type jsonHandler struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (h *jsonHandler) Handle(r Record) error {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	writeJSON(&b, r.Time.Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, r.Level.String())
+	if caller := shortCaller(r.PC); caller != "" {
+		b.WriteString(`,"caller":`)
+		writeJSON(&b, caller)
+	}
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, r.Message)
+	for _, f := range r.Fields {
+		b.WriteByte(',')
+		writeJSON(&b, f.Key)
+		b.WriteByte(':')
+		writeJSON(&b, jsonValue(f.Value))
+	}
+	b.WriteString("}\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(b.Bytes())
+	return err
+}
+
+// jsonValue converts values that json.Marshal would encode unhelpfully.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
+
+// writeJSON encodes v, falling back to its %v string if it cannot be encoded.
+func writeJSON(b *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(data)
+}
+
+// shortCaller returns file:line for pc, or "" if it is unknown.
+func shortCaller(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return ""
+	}
+	return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+}
+
+// NewSlogHandler passes records on to a log/slog handler, so the logger can
+// feed an existing slog pipeline. Levels map to the slog levels of the same
+// name and fields become attributes.
+func NewSlogHandler(h slog.Handler) Handler {
+	return slogHandler{h}
+}
+
+type slogHandler struct {
+	h slog.Handler
+}
+
+func (s slogHandler) Handle(r Record) error {
+	level := SlogLevel(r.Level)
+	ctx := context.Background()
+	if !s.h.Enabled(ctx, level) {
+		return nil
+	}
+	record := slog.NewRecord(r.Time, level, r.Message, r.PC)
+	for _, f := range r.Fields {
+		record.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	return s.h.Handle(ctx, record)
+}
+
+// SlogLevel converts a Level to the log/slog level of the same name.
+func SlogLevel(level Level) slog.Level {
+	switch level {
+	case Debug:
+		return slog.LevelDebug
+	case Warn:
+		return slog.LevelWarn
+	case Error:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -4,7 +4,9 @@ package logger
 import (
 	"fmt"
 	"io"
-	"log"
+	"runtime"
+	"sync/atomic"
+	"time"
 	//"os"
 )
 
@@ -34,59 +36,176 @@ func (l Level) String() string {
 	}
 }
 
+// Field is a key-value pair attached to a log record.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Record is a single log message as passed to a Handler.
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field // Logger fields from With, then the call's own fields
+	PC      uintptr // Program counter of the logging call, or 0
+}
+
+// Handler writes log records, for example as text or JSON. Handlers must be
+// safe for concurrent use.
+type Handler interface {
+	Handle(r Record) error
+}
+
 // Logger is a structured logger with support for log levels.
 type Logger struct {
-	level  Level
-	logger *log.Logger
+	core   *core   // Shared by loggers derived with With
+	fields []Field // Fields added by With
+}
+
+// core is the state shared by a logger and those derived from it.
+type core struct {
+	level   atomic.Int32
+	handler Handler
 }
 
-// New creates a new Logger with the specified log level and output.
+// New creates a new Logger with the specified log level and output. Messages
+// are written as text lines.
 func New(level Level, out io.Writer) *Logger {
+	return NewWithHandler(level, NewTextHandler(out))
+}
+
+// NewWithHandler creates a Logger that passes records of at least the given
+// level to handler.
+func NewWithHandler(level Level, handler Handler) *Logger {
+	l := &Logger{core: &core{handler: handler}}
+	l.core.level.Store(int32(level))
+	return l
+}
+
+// With returns a logger that adds the given fields, as alternating keys and
+// values, to every record. It shares its level and handler with l.
+func (l *Logger) With(keyValues ...interface{}) *Logger {
 	return &Logger{
-		level:  level,
-		logger: log.New(out, "", log.Ldate|log.Ltime|log.Lshortfile),
+		core:   l.core,
+		fields: appendFields(l.fields[:len(l.fields):len(l.fields)], keyValues),
 	}
 }
 
+// Level returns the minimum level that is logged.
+func (l *Logger) Level() Level {
+	return Level(l.core.level.Load())
+}
+
+// SetLevel changes the minimum level that is logged. It is safe to call while
+// other goroutines log, and affects every logger derived from the same New
+// call.
+func (l *Logger) SetLevel(level Level) {
+	l.core.level.Store(int32(level))
+}
+
+// Enabled reports whether messages at level are logged. Use it to skip
+// building expensive arguments.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
 // Debugf logs a debug message.
 func (l *Logger) Debugf(format string, v ...interface{}) {
-	if l.level <= Debug {
-		l.logf("DEBUG", format, v...)
+	if l.Enabled(Debug) {
+		l.log(Debug, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 // Infof logs an info message.
 func (l *Logger) Infof(format string, v ...interface{}) {
-	if l.level <= Info {
-		l.logf("INFO", format, v...)
+	if l.Enabled(Info) {
+		l.log(Info, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 // Warnf logs a warning message.
 func (l *Logger) Warnf(format string, v ...interface{}) {
-	if l.level <= Warn {
-		l.logf("WARN", format, v...)
+	if l.Enabled(Warn) {
+		l.log(Warn, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 // Errorf logs an error message.
 func (l *Logger) Errorf(format string, v ...interface{}) {
-	if l.level <= Error {
-		l.logf("ERROR", format, v...)
+	if l.Enabled(Error) {
+		l.log(Error, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+// Debug logs a debug message with fields given as alternating keys and values.
+func (l *Logger) Debug(msg string, keyValues ...interface{}) {
+	if l.Enabled(Debug) {
+		l.log(Debug, msg, keyValues)
+	}
+}
+
+// Info logs an info message with fields given as alternating keys and values.
+func (l *Logger) Info(msg string, keyValues ...interface{}) {
+	if l.Enabled(Info) {
+		l.log(Info, msg, keyValues)
+	}
+}
+
+// Warn logs a warning with fields given as alternating keys and values.
+func (l *Logger) Warn(msg string, keyValues ...interface{}) {
+	if l.Enabled(Warn) {
+		l.log(Warn, msg, keyValues)
+	}
+}
+
+// Error logs an error message with fields given as alternating keys and values.
+func (l *Logger) Error(msg string, keyValues ...interface{}) {
+	if l.Enabled(Error) {
+		l.log(Error, msg, keyValues)
 	}
 }
 
 // Panicf logs a message and panics.
 func (l *Logger) Panicf(format string, v ...interface{}) {
 	msg := fmt.Sprintf(format, v...)
-	l.logger.Output(2, fmt.Sprintf("PANIC: %s", msg))
+	l.log(Error, "PANIC: "+msg, nil)
 	panic(msg)
 }
 
-// logf formats the log message with the level prefix.
-func (l *Logger) logf(level string, format string, v ...interface{}) {
-	msg := fmt.Sprintf(format, v...)
-	l.logger.Output(3, fmt.Sprintf("[%s] %s", level, msg))
+// log builds a record and passes it to the handler. It must be called
+// directly by the exported logging method so the caller is found.
+func (l *Logger) log(level Level, msg string, keyValues []interface{}) {
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // runtime.Callers, log, the logging method
+	r := Record{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Fields:  l.fields,
+		PC:      pcs[0],
+	}
+	if len(keyValues) > 0 {
+		r.Fields = appendFields(l.fields[:len(l.fields):len(l.fields)], keyValues)
+	}
+	l.core.handler.Handle(r)
+}
+
+// appendFields converts alternating keys and values to fields. A key without
+// a value gets the value "MISSING"; a non-string key is formatted with %v.
+func appendFields(fields []Field, keyValues []interface{}) []Field {
+	for i := 0; i < len(keyValues); i += 2 {
+		key, ok := keyValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyValues[i])
+		}
+		var value interface{} = "MISSING"
+		if i+1 < len(keyValues) {
+			value = keyValues[i+1]
+		}
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	return fields
 }
 
 // ParseLevel converts a string to a log Level (case-insensitive).
@@ -103,4 +222,4 @@ func ParseLevel(s string) (Level, error) {
 	default:
 		return Info, fmt.Errorf("invalid log level: %s", s)
 	}
-}
\ No newline at end of file
+}
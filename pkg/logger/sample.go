@@ -0,0 +1,68 @@
+// pkg/logger/sample.go
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// NewSampledHandler limits how often each logging call site reaches h. In
+// every interval, the first `first` records from a call site at a given
+// level are passed on, then only every `thereafter`th one; thereafter <= 0
+// drops the rest of the interval. Records without a call site are never
+// dropped. Use it to keep chatty hot paths from flooding the output.
+func NewSampledHandler(h Handler, interval time.Duration, first, thereafter int) Handler {
+	return &sampledHandler{
+		next:       h,
+		interval:   interval,
+		first:      first,
+		thereafter: thereafter,
+		counts:     make(map[sampleKey]*sampleCount),
+	}
+}
+
+type sampledHandler struct {
+	next       Handler
+	interval   time.Duration
+	first      int
+	thereafter int
+
+	mu     sync.Mutex
+	counts map[sampleKey]*sampleCount
+}
+
+// sampleKey identifies a call site and level.
+type sampleKey struct {
+	pc    uintptr
+	level Level
+}
+
+// sampleCount counts a call site's records in the current interval.
+type sampleCount struct {
+	start time.Time
+	n     int
+}
+
+func (h *sampledHandler) Handle(r Record) error {
+	if r.PC != 0 && !h.allow(sampleKey{r.PC, r.Level}, r.Time) {
+		return nil
+	}
+	return h.next.Handle(r)
+}
+
+// allow counts a record and reports whether it should be passed on.
+func (h *sampledHandler) allow(key sampleKey, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, ok := h.counts[key]
+	if !ok || now.Sub(c.start) >= h.interval {
+		c = &sampleCount{start: now}
+		h.counts[key] = c
+	}
+	c.n++
+	if c.n <= h.first {
+		return true
+	}
+	return h.thereafter > 0 && (c.n-h.first)%h.thereafter == 0
+}
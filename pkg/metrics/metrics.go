@@ -0,0 +1,236 @@
+// pkg/metrics/metrics.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, suited to in-memory
+// tree operations and file saves alike.
+var DefaultBuckets = []float64{
+	0.000001, 0.000005, 0.00001, 0.00005, 0.0001, 0.0005,
+	0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5,
+}
+
+// Counter is a monotonically increasing count, safe for concurrent use.
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Histogram counts observations in cumulative buckets, safe for concurrent
+// use.
+type Histogram struct {
+	bounds []float64
+	counts []atomic.Uint64 // Per bucket, not cumulative; the last is +Inf
+	count  atomic.Uint64
+	sum    atomic.Uint64 // float64 bits
+}
+
+// NewHistogram creates a histogram with the given ascending upper bounds.
+// Nil bounds mean DefaultBuckets.
+func NewHistogram(bounds []float64) *Histogram {
+	if bounds == nil {
+		bounds = DefaultBuckets
+	}
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)+1),
+	}
+}
+
+// Observe records a value.
+func (h *Histogram) Observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.bounds, v)].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// ObserveSince records the seconds elapsed since start.
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// Registry holds named metrics and writes them in the Prometheus text
+// exposition format. Metrics sharing a name form a family and must differ in
+// their labels; registering the same name and labels again replaces the
+// earlier metric.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// family is all series of one metric name.
+type family struct {
+	help   string
+	kind   string // counter, gauge or histogram
+	series map[string]func(w io.Writer, name, labels string)
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Counter registers c under name. Labels are given as name, value pairs.
+func (r *Registry) Counter(name, help string, c *Counter, labels ...string) {
+	r.register(name, help, "counter", labels, func(w io.Writer, name, labels string) {
+		fmt.Fprintf(w, "%s%s %d\n", name, labels, c.Value())
+	})
+}
+
+// CounterFunc registers a counter whose value is read from fn at scrape time.
+func (r *Registry) CounterFunc(name, help string, fn func() float64, labels ...string) {
+	r.register(name, help, "counter", labels, func(w io.Writer, name, labels string) {
+		fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(fn()))
+	})
+}
+
+// GaugeFunc registers a gauge whose value is read from fn at scrape time.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64, labels ...string) {
+	r.register(name, help, "gauge", labels, func(w io.Writer, name, labels string) {
+		fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(fn()))
+	})
+}
+
+// Histogram registers h under name.
+func (r *Registry) Histogram(name, help string, h *Histogram, labels ...string) {
+	r.register(name, help, "histogram", labels, func(w io.Writer, name, labels string) {
+		// Read the buckets first so the +Inf bucket never exceeds _count.
+		cumulative := uint64(0)
+		for i, bound := range h.bounds {
+			cumulative += h.counts[i].Load()
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", formatFloat(bound)), cumulative)
+		}
+		cumulative += h.counts[len(h.bounds)].Load()
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), cumulative)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(math.Float64frombits(h.sum.Load())))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, labels, cumulative)
+	})
+}
+
+// register adds a series to the named family, creating the family if needed.
+func (r *Registry) register(name, help, kind string, labels []string, write func(w io.Writer, name, labels string)) {
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf("metrics: odd number of label arguments for %s", name))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{help: help, kind: kind, series: make(map[string]func(io.Writer, string, string))}
+		r.families[name] = f
+	} else if f.kind != kind {
+		panic(fmt.Sprintf("metrics: %s registered as both %s and %s", name, f.kind, kind))
+	}
+	f.series[formatLabels(labels)] = write
+}
+
+// WriteText writes every metric in the Prometheus text exposition format,
+// sorted by name and labels.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, f.kind)
+		labels := make([]string, 0, len(f.series))
+		for l := range f.series {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			f.series[l](&b, name, l)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the metrics for scraping.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+// formatLabels renders name, value pairs as {a="1",b="2"}, or "" for none.
+func formatLabels(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+"="+strconv.Quote(pairs[i+1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// withLabel appends one label to a rendered label set.
+func withLabel(labels, name, value string) string {
+	label := name + "=" + strconv.Quote(value)
+	if labels == "" {
+		return "{" + label + "}"
+	}
+	return labels[:len(labels)-1] + "," + label + "}"
+}
+
+// formatFloat renders a sample value as Prometheus expects.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeHelp escapes backslashes and newlines in HELP text.
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
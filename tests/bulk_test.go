@@ -0,0 +1,80 @@
+package tree_test
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+)
+
+func TestBulkLoadDegrees(t *testing.T) {
+	for _, degree := range []int{2, 3, 4, 5, 8, 50} {
+		for _, n := range []int{0, 1, 2, 3, 7, 100, 1001} {
+			t.Run(fmt.Sprintf("degree=%d/n=%d", degree, n), func(t *testing.T) {
+				tr := tree.NewTree(degree, logger.New(logger.Error, io.Discard))
+				keys := make([]int, n)
+				values := make([]interface{}, n)
+				for i := range keys {
+					keys[i] = i * 2
+					values[i] = fmt.Sprintf("v%d", i*2)
+				}
+				if err := tr.BulkLoad(keys, values); err != nil {
+					t.Fatal(err)
+				}
+
+				if tr.Size != n {
+					t.Errorf("Size = %d, want %d", tr.Size, n)
+				}
+				if !tr.ValidateTree() {
+					t.Fatal("tree is invalid after bulk load")
+				}
+				got := treeKeys(tr)
+				if n == 0 {
+					got = []int{}
+				}
+				if !reflect.DeepEqual(got, keys) {
+					t.Fatalf("keys = %v, want %v", got, keys)
+				}
+				for i, key := range keys {
+					if value, found := tr.Search(key); !found || value != values[i] {
+						t.Fatalf("Search(%d) = %v, %v", key, value, found)
+					}
+				}
+
+				// The loaded tree must keep working as an ordinary tree.
+				for i := 0; i < n; i++ {
+					tr.Insert(i*2+1, "odd")
+				}
+				for i := 0; i < n; i += 2 {
+					tr.Delete(i * 2)
+				}
+				if !tr.ValidateTree() {
+					t.Fatal("tree is invalid after updates")
+				}
+				if want := n + n/2; tr.Size != want {
+					t.Errorf("Size after updates = %d, want %d", tr.Size, want)
+				}
+			})
+		}
+	}
+}
+
+func TestBulkLoadRejects(t *testing.T) {
+	tr := newTestTree()
+	if err := tr.BulkLoad([]int{1, 3, 2}, []interface{}{1, 3, 2}); err == nil {
+		t.Error("unsorted keys were accepted")
+	}
+	if err := tr.BulkLoad([]int{1, 1}, []interface{}{1, 1}); err == nil {
+		t.Error("duplicate keys were accepted")
+	}
+	if err := tr.BulkLoad([]int{1, 2}, []interface{}{1}); err == nil {
+		t.Error("mismatched values were accepted")
+	}
+	tr.Insert(5, 5)
+	if err := tr.BulkLoad([]int{1}, []interface{}{1}); err == nil {
+		t.Error("bulk load into a non-empty tree was accepted")
+	}
+}
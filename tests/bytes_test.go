@@ -0,0 +1,188 @@
+package tree_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/keys"
+	"elastic-btree/pkg/logger"
+)
+
+func bytesTreeKeys(tr *tree.BytesTree, from, to []byte) []string {
+	var got []string
+	tr.AscendRange(from, to, func(key []byte, _ interface{}) bool {
+		got = append(got, string(key))
+		return true
+	})
+	return got
+}
+
+func TestBytesTreeMatchesModel(t *testing.T) {
+	for _, degree := range []int{2, 3, 8} {
+		rng := rand.New(rand.NewSource(int64(degree)))
+		tr := tree.NewBytesTree(degree, logger.New(logger.Error, io.Discard))
+		model := make(map[string]int)
+
+		for step := 0; step < 4000; step++ {
+			// Shared prefixes of varying length exercise the compression.
+			key := []byte(fmt.Sprintf("tenant-%d/item-%d", rng.Intn(3), rng.Intn(200)))
+			if rng.Intn(3) == 0 {
+				_, found := tr.Delete(key)
+				_, want := model[string(key)]
+				if found != want {
+					t.Fatalf("degree %d step %d: Delete(%s) found = %v, want %v", degree, step, key, found, want)
+				}
+				delete(model, string(key))
+			} else {
+				tr.Put(key, step)
+				model[string(key)] = step
+			}
+			if err := tr.Validate(); err != nil {
+				t.Fatalf("degree %d step %d: %v", degree, step, err)
+			}
+		}
+
+		var want []string
+		for key := range model {
+			want = append(want, key)
+		}
+		sort.Strings(want)
+		if got := bytesTreeKeys(tr, nil, nil); !reflect.DeepEqual(got, want) {
+			t.Fatalf("degree %d: keys differ from model (%d vs %d)", degree, len(got), len(want))
+		}
+		for key, value := range model {
+			if got, found := tr.Get([]byte(key)); !found || got != value {
+				t.Fatalf("degree %d: Get(%s) = %v, %v, want %v", degree, key, got, found, value)
+			}
+		}
+	}
+}
+
+func TestBytesTreeRangesAndCompression(t *testing.T) {
+	tr := tree.NewBytesTree(4, logger.New(logger.Error, io.Discard))
+	for i := 0; i < 1000; i++ {
+		tr.Put([]byte(fmt.Sprintf("customers/eu-west/%06d", i)), i)
+	}
+
+	got := bytesTreeKeys(tr, []byte("customers/eu-west/000998"), nil)
+	if want := []string{"customers/eu-west/000998", "customers/eu-west/000999"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("open-ended range = %v, want %v", got, want)
+	}
+	if got := bytesTreeKeys(tr, []byte("customers/eu-west/000010"), []byte("customers/eu-west/000012")); len(got) != 3 {
+		t.Errorf("closed range returned %d keys, want 3", len(got))
+	}
+
+	// Stored key bytes should be far below the raw key bytes.
+	var stored, raw int
+	var walk func(n *tree.BytesNode)
+	walk = func(n *tree.BytesNode) {
+		stored += len(n.Prefix)
+		for _, suffix := range n.Suffixes {
+			stored += len(suffix)
+			if n.IsLeaf {
+				raw += len(n.Prefix) + len(suffix)
+			}
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(tr.Root)
+	if stored*2 > raw {
+		t.Errorf("stored %d key bytes for %d raw bytes; expected prefix compression", stored, raw)
+	}
+
+	path := filepath.Join(t.TempDir(), "bytes.json")
+	store := storage.NewStorage(path)
+	if err := store.SaveBytesTree(tr); err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	loaded, err := store.LoadBytesTree(logger.New(logger.Debug, &logs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Root.Prefix, tr.Root.Prefix) || loaded.Len() != 1000 {
+		t.Errorf("loaded tree lost its compressed layout or keys")
+	}
+	if value, found := loaded.Get([]byte("customers/eu-west/000500")); !found || value != float64(500) {
+		t.Errorf("loaded Get = %v, %v", value, found)
+	}
+
+	// The loaded tree logs its root splits through the given logger, at
+	// debug level.
+	for i := 1000; i < 30000; i++ {
+		loaded.Put([]byte(fmt.Sprintf("customers/eu-west/%06d", i)), i)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 30000 {
+		t.Errorf("loaded tree has %d keys after writes, want 30000", loaded.Len())
+	}
+	if !strings.Contains(logs.String(), "[DEBUG] BytesTree: root split") {
+		t.Errorf("root split not logged at debug level: %q", logs.String())
+	}
+}
+
+func TestTupleKeysPreserveOrder(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tuples := [][]interface{}{
+		{"acme", base, int64(math.MinInt64)},
+		{"acme", base, int64(-1)},
+		{"acme", base, int64(0)},
+		{"acme", base, int64(7)},
+		{"acme", base.Add(time.Nanosecond), int64(-5)},
+		{"acme", base.Add(time.Hour), int64(0)},
+		{"acme\x00", base, int64(0)},
+		{"acmf", base.Add(-time.Hour), int64(0)},
+		{"b", base, int64(0)},
+	}
+
+	tr := tree.NewBytesTree(2, logger.New(logger.Error, io.Discard))
+	for i := len(tuples) - 1; i >= 0; i-- {
+		tr.Put(keys.MustEncode(tuples[i]...), i)
+	}
+	var order []int
+	tr.Ascend(func(key []byte, value interface{}) bool {
+		order = append(order, value.(int))
+		decoded, err := keys.Decode(key)
+		if err != nil || !reflect.DeepEqual(decoded, tuples[value.(int)]) {
+			t.Errorf("Decode = %v, %v; want %v", decoded, err, tuples[value.(int)])
+		}
+		return true
+	})
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8}; !reflect.DeepEqual(order, want) {
+		t.Errorf("tuple order %v, want %v", order, want)
+	}
+
+	var tenant []int
+	tr.AscendPrefix(keys.MustEncode("acme"), func(_ []byte, value interface{}) bool {
+		tenant = append(tenant, value.(int))
+		return true
+	})
+	if len(tenant) != 6 {
+		t.Errorf("prefix scan for tenant acme returned %v", tenant)
+	}
+
+	if _, err := keys.Encode(3.5); err == nil {
+		t.Error("Encode accepted a float")
+	}
+	if _, err := keys.Decode(keys.MustEncode("x")[:2]); err == nil {
+		t.Error("Decode accepted a truncated key")
+	}
+}
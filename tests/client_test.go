@@ -0,0 +1,129 @@
+package tree_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/server"
+	"elastic-btree/internal/storage"
+	"elastic-btree/pkg/client"
+)
+
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	tr := newTestTree()
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	handler := server.New(tr, store, tr.Logger).Handler()
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestClientOperations(t *testing.T) {
+	ts := newTestServer(t, nil)
+	c := client.New(ts.URL, client.Options{PageSize: 7})
+	defer c.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		if err := c.Insert(ctx, i, i*10); err != nil {
+			t.Fatalf("Insert(%d): %v", i, err)
+		}
+	}
+
+	if value, found, err := c.Search(ctx, 42); err != nil || !found || value != float64(420) {
+		t.Errorf("Search(42) = %v, %v, %v", value, found, err)
+	}
+	if _, found, err := c.Search(ctx, 1000); err != nil || found {
+		t.Errorf("Search(1000) found = %v, err = %v", found, err)
+	}
+	if found, err := c.Delete(ctx, 42); err != nil || !found {
+		t.Errorf("Delete(42) = %v, %v", found, err)
+	}
+	if found, err := c.Delete(ctx, 42); err != nil || found {
+		t.Errorf("second Delete(42) = %v, %v", found, err)
+	}
+
+	var keys []int
+	err := c.Ascend(ctx, 30, 60, func(key int, _ interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Ascend: %v", err)
+	}
+	if len(keys) != 30 || keys[0] != 30 || keys[len(keys)-1] != 60 {
+		t.Errorf("Ascend(30, 60) returned %d keys: %v", len(keys), keys)
+	}
+
+	results, err := c.Batch(ctx, []client.Op{
+		{Kind: client.OpPut, Key: 500, Value: "x"},
+		{Kind: client.OpGet, Key: 500},
+		{Kind: client.OpDelete, Key: 1},
+	})
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if len(results) != 3 || results[1].Value != "x" || !results[2].Found {
+		t.Errorf("Batch results = %+v", results)
+	}
+
+	stats, err := c.Stats(ctx)
+	if err != nil || stats.Size != 99 {
+		t.Errorf("Stats = %+v, %v", stats, err)
+	}
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	ts := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := client.New(ts.URL, client.Options{Backoff: time.Millisecond})
+	defer c.Close()
+
+	if err := c.Insert(context.Background(), 1, "a"); err != nil {
+		t.Fatalf("Insert after transient failures: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("server called %d times, want 3", calls.Load())
+	}
+}
+
+func TestClientHonoursContextDeadline(t *testing.T) {
+	ts := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		})
+	})
+	c := client.New(ts.URL, client.Options{})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := c.Search(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Search error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Search took %v despite 50ms deadline", elapsed)
+	}
+}
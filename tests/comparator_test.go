@@ -0,0 +1,241 @@
+package tree_test
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/replication"
+	"elastic-btree/internal/server"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+)
+
+func TestComparatorPersisted(t *testing.T) {
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+
+	tr := newTestTree()
+	if err := tr.SetComparator(tree.Descending); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		tr.Insert(i, i)
+	}
+	if err := tr.SetComparator(tree.Ascending); err == nil {
+		t.Error("SetComparator succeeded on a non-empty tree")
+	}
+	if err := store.SaveTree(tr); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.LoadTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.Logger = tr.Logger
+	if !loaded.ValidateTree() {
+		t.Fatal("descending tree failed validation after loading")
+	}
+	if got, want := treeKeys(loaded), treeKeys(tr); !reflect.DeepEqual(got, want) || got[0] != 499 {
+		t.Errorf("loaded keys start %v, want descending order", got[:3])
+	}
+	if _, found := loaded.Search(250); !found {
+		t.Error("Search(250) failed on loaded tree")
+	}
+}
+
+func TestComparatorUnregistered(t *testing.T) {
+	byLastDigit := func(a, b int) int {
+		if a%10 != b%10 {
+			return a%10 - b%10
+		}
+		return a - b
+	}
+	if err := tree.RegisterComparator("test-last-digit", byLastDigit); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.RegisterComparator("test-last-digit", byLastDigit); err == nil {
+		t.Error("registering a name twice succeeded")
+	}
+
+	tr := newTestTree()
+	if err := tr.SetComparator("test-last-digit"); err != nil {
+		t.Fatal(err)
+	}
+	tr.Insert(21, nil)
+	tr.Insert(12, nil)
+	data, err := storage.EncodeTree(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.DecodeTree(data); err != nil {
+		t.Errorf("decoding with a registered comparator: %v", err)
+	}
+
+	// Simulate a program that never registered the comparator.
+	tr.ComparatorName = "test-missing"
+	data, _ = storage.EncodeTree(tr)
+	_, err = storage.DecodeTree(data)
+	var unknown *tree.UnknownComparatorError
+	if !errors.As(err, &unknown) || unknown.Name != "test-missing" {
+		t.Errorf("DecodeTree error = %v, want UnknownComparatorError", err)
+	}
+}
+
+func TestDefaultComparatorExtremes(t *testing.T) {
+	tr := newTestTree()
+	keys := []int{math.MaxInt, math.MinInt, 0, -1, 1, math.MaxInt - 1, math.MinInt + 1}
+	for _, key := range keys {
+		tr.Insert(key, key)
+	}
+	want := []int{math.MinInt, math.MinInt + 1, -1, 0, 1, math.MaxInt - 1, math.MaxInt}
+	if got := treeKeys(tr); !reflect.DeepEqual(got, want) {
+		t.Errorf("keys %v, want %v", got, want)
+	}
+	for _, key := range keys {
+		if _, found := tr.Search(key); !found {
+			t.Errorf("Search(%d) failed", key)
+		}
+	}
+}
+
+func TestDebugComparatorRejectsBrokenOrder(t *testing.T) {
+	// Rock-paper-scissors on key%3: every pair is ordered but not transitively.
+	rps := func(a, b int) int {
+		switch (b%3 - a%3 + 3) % 3 {
+		case 0:
+			return 0
+		case 1:
+			return -1
+		}
+		return 1
+	}
+	// Every distinct key is smaller than every other.
+	alwaysLess := func(a, b int) int {
+		if a == b {
+			return 0
+		}
+		return -1
+	}
+
+	for name, cmp := range map[string]func(a, b int) int{"transitivity": rps, "antisymmetry": alwaysLess} {
+		tr := newTestTree()
+		tr.Comparator = cmp
+		tr.DebugComparator = true
+		for i := 0; i < 3; i++ {
+			tr.Insert(i, i)
+		}
+
+		var cmpErr *tree.ComparatorError
+		if err := tr.ComparatorErr(); !errors.As(err, &cmpErr) {
+			t.Errorf("%s: ComparatorErr() = %v, want *ComparatorError", name, err)
+			continue
+		}
+		if cmpErr.Property != name {
+			t.Errorf("%s: reported %s", name, cmpErr.Property)
+		}
+		if !tr.ValidateTree() {
+			t.Errorf("%s: tree corrupted despite the checks", name)
+		}
+	}
+
+	if err := tree.CheckComparator(func(a, b int) int { return a - b }, []int{math.MinInt, 0, 1}); err == nil {
+		t.Error("CheckComparator accepted an overflowing comparator")
+	}
+}
+
+func TestDebugComparatorRejectionReported(t *testing.T) {
+	// Every distinct key is smaller than every other.
+	alwaysLess := func(a, b int) int {
+		if a == b {
+			return 0
+		}
+		return -1
+	}
+	tr := newTestTree()
+	tr.Comparator = alwaysLess
+	tr.DebugComparator = true
+
+	if err := tr.InsertChecked(0, "a"); err != nil {
+		t.Fatalf("InsertChecked(0) = %v", err)
+	}
+	var cmpErr *tree.ComparatorError
+	if err := tr.InsertChecked(1, "b"); !errors.As(err, &cmpErr) {
+		t.Errorf("InsertChecked(1) = %v, want *ComparatorError", err)
+	}
+	if _, _, err := tr.PutChecked(2, "c"); !errors.As(err, &cmpErr) {
+		t.Errorf("PutChecked(2) = %v, want *ComparatorError", err)
+	}
+	if _, stored, err := tr.PutIf(3, "d", false); stored || !errors.As(err, &cmpErr) {
+		t.Errorf("PutIf(3) = %v, %v, want not stored and *ComparatorError", stored, err)
+	}
+	if _, _, err := tr.InsertWithTTLChecked(6, "g", time.Minute); !errors.As(err, &cmpErr) {
+		t.Errorf("InsertWithTTLChecked(6) = %v, want *ComparatorError", err)
+	}
+
+	// A rejected write through a replication leader is reported to the
+	// client and never logged.
+	leader := replication.NewLeader(tr, tr.Logger, 0)
+	srv := server.New(tr, storage.NewStorage(filepath.Join(t.TempDir(), "tree.json")), tr.Logger)
+	srv.SetWriter(leader)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status := doRequest(t, "PUT", ts.URL+"/keys/4", map[string]interface{}{"value": "e"}, nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("PUT of a rejected key: status = %d, want 422", status)
+	}
+	if status := doRequest(t, "PUT", ts.URL+"/keys/7?ttl=1m", map[string]interface{}{"value": "h"}, nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("PUT with a TTL of a rejected key: status = %d, want 422", status)
+	}
+	client, err := server.DialRESP(startRESP(t, srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if reply, err := client.Do("SET", "8", "i", "EX", "60"); err == nil {
+		t.Errorf("SET EX of a rejected key = %v, want an error", reply)
+	}
+	var results []server.BatchResult
+	doRequest(t, "POST", ts.URL+"/batch", []server.BatchOp{{Op: "put", Key: 5, Value: "f"}}, &results)
+	if len(results) != 1 || results[0].Error == "" || results[0].Found {
+		t.Errorf("batch put of a rejected key = %+v, want an error", results)
+	}
+	if seq := leader.Seq(); seq != 0 {
+		t.Errorf("leader logged %d entries for rejected writes", seq)
+	}
+	if got, want := treeKeys(tr), []int{0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+}
+
+func TestComparatorUnnamedNotSaved(t *testing.T) {
+	s := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	tr := newTestTree()
+	tr.Comparator = func(a, b int) int { return b - a }
+	tr.Insert(1, nil)
+	tr.Insert(2, nil)
+	if err := s.SaveTree(tr); err == nil {
+		t.Fatal("SaveTree accepted a comparator without a name")
+	}
+	if _, err := s.LoadTree(); err == nil {
+		t.Error("a tree file was written")
+	}
+
+	// The default comparator and registered ones save as before.
+	if err := s.SaveTree(newTestTree()); err != nil {
+		t.Errorf("saving the default comparator: %v", err)
+	}
+	named := newTestTree()
+	if err := named.SetComparator(tree.Descending); err != nil {
+		t.Fatal(err)
+	}
+	named.Insert(1, nil)
+	if err := s.SaveTree(named); err != nil {
+		t.Errorf("saving a named comparator: %v", err)
+	}
+}
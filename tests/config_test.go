@@ -0,0 +1,130 @@
+package tree_test
+
+import (
+	"elastic-btree/internal/storage"
+	"elastic-btree/pkg/config"
+	"elastic-btree/pkg/logger"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "btree.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `{"degree": 8, "logLevel": "warn", "checkpoint": "10s", "fsync": "always"}`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("CHECKPOINT_INTERVAL", "20s")
+
+	cfg, rest, err := config.Parse([]string{"--checkpoint", "5s", "serve", "--addr", ":1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rest, []string{"serve", "--addr", ":1"}) {
+		t.Errorf("remaining args = %v", rest)
+	}
+	if cfg.File != path {
+		t.Errorf("File = %q, want %q", cfg.File, path)
+	}
+
+	checks := []struct {
+		name   string
+		got    interface{}
+		want   interface{}
+		source config.Source
+	}{
+		{"degree", cfg.TreeDegree, 8, config.FromFile},
+		{"fsync", cfg.Fsync, "always", config.FromFile},
+		{"logLevel", cfg.LogLevel, logger.Error, config.FromEnv},
+		{"checkpoint", cfg.Checkpoint, 5 * time.Second, config.FromFlag},
+		{"httpAddr", cfg.HTTPAddr, ":8080", config.FromDefault},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+		if source := cfg.Source(c.name); source != c.source {
+			t.Errorf("%s source = %s, want %s", c.name, source, c.source)
+		}
+	}
+
+	for _, s := range cfg.Settings() {
+		if s.Name == "checkpoint" && (s.Value != "5s" || s.Flag != "checkpoint" || s.Env != "CHECKPOINT_INTERVAL") {
+			t.Errorf("checkpoint setting = %+v", s)
+		}
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	tests := []struct {
+		file string
+		env  [2]string
+		args []string
+		want string
+	}{
+		{args: []string{"--degree", "1"}, want: "invalid --degree"},
+		{args: []string{"--fsync", "sometimes"}, want: "invalid --fsync"},
+		{args: []string{"--http-addr", "nowhere"}, want: "invalid --http-addr"},
+		{args: []string{"--no-such-flag"}, want: "no-such-flag"},
+		{env: [2]string{"INVARIANT_MODE", "most"}, want: "invalid INVARIANT_MODE"},
+		{env: [2]string{"CHECKPOINT_INTERVAL", "-1s"}, want: "invalid CHECKPOINT_INTERVAL"},
+		{file: `{"degre": 8}`, want: `unknown option "degre"`},
+		{file: `{"maxEntries": "many"}`, want: "invalid maxEntries"},
+		{file: `{"maxEntries": -2}`, want: "invalid maxEntries"},
+		{file: `{"storageBackend": "s3"}`, want: "invalid storageBackend"},
+		{file: `[1, 2]`, want: "invalid config file"},
+	}
+	for _, tt := range tests {
+		args := tt.args
+		if tt.file != "" {
+			args = append([]string{"--config", writeConfig(t, tt.file)}, args...)
+		}
+		if tt.env[0] != "" {
+			t.Setenv(tt.env[0], tt.env[1])
+		}
+		_, _, err := config.Parse(args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%v) with file %q, env %v: error %v, want %q", tt.args, tt.file, tt.env, err, tt.want)
+		}
+		if tt.env[0] != "" {
+			os.Unsetenv(tt.env[0])
+		}
+	}
+}
+
+func TestStorageSyncSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.json")
+	store := storage.NewStorage(path)
+	store.SetSync(true)
+
+	tr := newTestTree()
+	for i := 0; i < 100; i++ {
+		tr.Insert(i, fmt.Sprint(i*i))
+	}
+	if err := store.SaveTree(tr); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.LoadTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := treeContents(loaded); !reflect.DeepEqual(got, treeContents(tr)) {
+		t.Errorf("loaded %d entries, want %d", len(got), len(treeContents(tr)))
+	}
+	if matches, _ := filepath.Glob(path + "*.tmp*"); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
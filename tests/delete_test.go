@@ -0,0 +1,41 @@
+package tree_test
+
+import (
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+)
+
+func TestDeleteMatchesModel(t *testing.T) {
+	for _, degree := range []int{2, 3, 5} {
+		rng := rand.New(rand.NewSource(int64(degree)))
+		tr := tree.NewTree(degree, logger.New(logger.Error, io.Discard))
+		tr.Invariants = tree.InvariantsFull
+		model := make(map[int]interface{})
+
+		for step := 0; step < 5000; step++ {
+			key := rng.Intn(300)
+			if rng.Intn(3) == 0 {
+				tr.Delete(key)
+				delete(model, key)
+			} else {
+				tr.Put(key, step)
+				model[key] = step
+			}
+
+			if tr.Size != len(model) {
+				t.Fatalf("degree %d step %d: size %d, want %d", degree, step, tr.Size, len(model))
+			}
+			if !tr.ValidateTree() {
+				t.Fatalf("degree %d step %d: tree failed validation", degree, step)
+			}
+		}
+		if got := treeContents(tr); !reflect.DeepEqual(got, model) {
+			t.Fatalf("degree %d: contents differ from model (%d keys vs %d)", degree, len(got), len(model))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package tree_test
+
+import (
+	"reflect"
+	"testing"
+
+	"elastic-btree/internal/tree"
+)
+
+func treeKeys(tr *tree.Tree) []int {
+	var keys []int
+	tr.Ascend(func(key int, _ interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	tests := []struct {
+		policy tree.EvictionPolicy
+		want   []int
+	}{
+		// Keys 1-5 are inserted, 1 and 2 are read (1 twice), then 6 and 7
+		// are inserted into a tree bounded to 5 entries.
+		{tree.EvictLRU, []int{1, 2, 5, 6, 7}},
+		{tree.EvictLFU, []int{1, 2, 5, 6, 7}},
+		{tree.EvictSmallest, []int{3, 4, 5, 6, 7}},
+	}
+	for _, tc := range tests {
+		tr := newTestTree()
+		tr.MaxEntries = 5
+		tr.Eviction = tc.policy
+		var evicted []int
+		tr.OnEvict = func(key int, _ interface{}) { evicted = append(evicted, key) }
+
+		for i := 1; i <= 5; i++ {
+			tr.Insert(i, i)
+		}
+		tr.Search(1)
+		tr.Search(2)
+		tr.Search(1)
+		if tc.policy == tree.EvictLFU {
+			// Make 5 more frequently used than 3 and 4 but less than 1 and 2.
+			tr.Search(5)
+		}
+		tr.Insert(6, 6)
+		tr.Insert(7, 7)
+
+		if got := treeKeys(tr); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: keys %v, want %v", tc.policy, got, tc.want)
+		}
+		if tr.Evictions != 2 || len(evicted) != 2 {
+			t.Errorf("%s: %d evictions, callback saw %v", tc.policy, tr.Evictions, evicted)
+		}
+	}
+}
+
+func TestEvictionMaxBytes(t *testing.T) {
+	tr := newTestTree()
+	tr.MaxBytes = 100
+
+	// Each entry is an 8-byte key plus a 12-byte string.
+	for i := 0; i < 20; i++ {
+		tr.Put(i, "abcdefghijkl")
+	}
+	if tr.Size != 5 || tr.EvictedBytes != 15*20 {
+		t.Errorf("size %d and %d evicted bytes, want 5 and 300", tr.Size, tr.EvictedBytes)
+	}
+
+	// Limits set on a populated tree apply on the next write.
+	tr.MaxBytes = 0
+	tr.MaxEntries = 2
+	tr.Eviction = tree.EvictSmallest
+	tr.Put(100, "x")
+	if keys := treeKeys(tr); !reflect.DeepEqual(keys, []int{19, 100}) {
+		t.Errorf("keys %v, want [19 100]", keys)
+	}
+}
+
+func TestEvictionDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		policy    tree.EvictionPolicy
+		want      []int // After the count limit
+		wantBytes []int // After the byte limit
+	}{
+		{tree.EvictLRU, []int{4, 5, 6}, []int{5, 6, 7}},
+		// Inserting key 1 twice counts as two accesses.
+		{tree.EvictLFU, []int{1, 1, 6}, []int{1, 1, 7}},
+		{tree.EvictSmallest, []int{4, 5, 6}, []int{5, 6, 7}},
+	}
+	for _, tc := range tests {
+		tr := newTestTree()
+		tr.MaxEntries = 3
+		tr.MaxBytes = 1000
+		tr.Eviction = tc.policy
+
+		// Every occurrence of a duplicate key is an entry that can be evicted.
+		tr.Insert(1, "a")
+		tr.Insert(1, "b")
+		for i := 2; i <= 6; i++ {
+			tr.Insert(i, "c")
+		}
+		if got := treeKeys(tr); !reflect.DeepEqual(got, tc.want) || tr.Size != 3 {
+			t.Errorf("%s: keys %v (size %d), want %v", tc.policy, got, tr.Size, tc.want)
+		}
+		if tr.Evictions != 4 || tr.EvictedBytes != 4*9 {
+			t.Errorf("%s: %d evictions of %d bytes, want 4 of 36", tc.policy, tr.Evictions, tr.EvictedBytes)
+		}
+
+		// The byte estimate follows the remaining entries: three 9-byte
+		// entries plus an 8-byte one is one entry too many.
+		tr.MaxEntries = 0
+		tr.MaxBytes = 27
+		tr.Insert(7, "")
+		if got := treeKeys(tr); !reflect.DeepEqual(got, tc.wantBytes) || tr.Size != 3 {
+			t.Errorf("%s: keys %v (size %d) after byte limit, want %v", tc.policy, got, tr.Size, tc.wantBytes)
+		}
+	}
+}
+
+func TestConfigureEviction(t *testing.T) {
+	tr := newTestTree()
+	for i := 1; i <= 5; i++ {
+		tr.Insert(i, i)
+	}
+
+	settings := tr.Settings()
+	settings.MaxEntries = 2
+	settings.Eviction = "fifo"
+	if err := tr.Configure(settings); err == nil {
+		t.Fatal("Configure accepted an unknown eviction policy")
+	}
+	if got := tr.Settings(); got.MaxEntries != 0 || got.Eviction != "" || tr.Size != 5 {
+		t.Fatalf("rejected Configure changed the tree: %+v, size %d", got, tr.Size)
+	}
+
+	settings.Eviction = tree.EvictSmallest
+	if err := tr.Configure(settings); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := treeKeys(tr), []int{4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys %v, want %v", got, want)
+	}
+}
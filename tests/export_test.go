@@ -0,0 +1,126 @@
+package tree_test
+
+import (
+	"bytes"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// newExportTree returns a small tree of degree 2 with several levels.
+func newExportTree() *tree.Tree {
+	tr := tree.NewTree(2, logger.New(logger.Error, io.Discard))
+	for i := 1; i <= 30; i++ {
+		tr.Insert(i, i)
+	}
+	return tr
+}
+
+func TestStructureMatchesTree(t *testing.T) {
+	tr := newExportTree()
+	s := tr.Structure(17)
+
+	var nodes, keys int
+	var check func(view *tree.NodeView, node *tree.Node, level int)
+	check = func(view *tree.NodeView, node *tree.Node, level int) {
+		nodes++
+		keys += len(view.Keys)
+		if view.Level != level || view.Leaf != node.IsLeaf || len(view.Children) != len(node.Children) {
+			t.Fatalf("node %d: level %d, leaf %v, %d children; want %d, %v, %d",
+				view.ID, view.Level, view.Leaf, len(view.Children), level, node.IsLeaf, len(node.Children))
+		}
+		for i, child := range view.Children {
+			check(child, node.Children[i], level+1)
+		}
+	}
+	check(s.Root, tr.Root, 0)
+	if keys != tr.Size || s.Size != tr.Size || s.Height != tr.Height {
+		t.Errorf("structure holds %d keys, size %d, height %d; tree has size %d, height %d",
+			keys, s.Size, s.Height, tr.Size, tr.Height)
+	}
+
+	// The path to 17 runs from the root to the node holding it.
+	var path []*tree.NodeView
+	for view := s.Root; view != nil; {
+		if len(view.Path) != 1 || view.Path[0] != 17 {
+			t.Fatalf("node %d on the path has Path %v", view.ID, view.Path)
+		}
+		path = append(path, view)
+		var next *tree.NodeView
+		for _, child := range view.Children {
+			if len(child.Path) > 0 {
+				next = child
+			}
+		}
+		view = next
+	}
+	last := path[len(path)-1]
+	if len(last.Found) != 1 || last.Found[0] != 17 || len(path) > tr.Height {
+		t.Errorf("path of %d nodes ends at %v (found %v)", len(path), last.Keys, last.Found)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	tr := newExportTree()
+	var out bytes.Buffer
+	if err := tr.WriteDOT(&out, 17); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+	if !strings.HasPrefix(dot, "digraph btree {") || !strings.HasSuffix(dot, "}\n") {
+		t.Fatalf("not a DOT graph:\n%s", dot)
+	}
+
+	nodes := regexp.MustCompile(`(?m)^\tn\d+ \[label=`).FindAllString(dot, -1)
+	edges := regexp.MustCompile(`(?m)^\tn\d+:c\d+ -> n\d+`).FindAllString(dot, -1)
+	stats := tr.Stats()
+	if len(nodes) != stats.Nodes || len(edges) != stats.Nodes-1 {
+		t.Errorf("%d nodes and %d edges, want %d and %d", len(nodes), len(edges), stats.Nodes, stats.Nodes-1)
+	}
+	highlighted := regexp.MustCompile(`(?m)^\tn\d+:c\d+ -> n\d+ \[color=`).FindAllString(dot, -1)
+	if len(highlighted) == 0 || len(highlighted) >= tr.Height {
+		t.Errorf("%d highlighted edges for a tree of height %d", len(highlighted), tr.Height)
+	}
+	if !strings.Contains(dot, "|[17]") && !strings.Contains(dot, "{[17]") {
+		t.Error("found key 17 is not marked")
+	}
+}
+
+func TestWriteStructureFormats(t *testing.T) {
+	tr := newExportTree()
+	s := tr.Structure(100)
+
+	var ascii bytes.Buffer
+	if err := s.WriteASCII(&ascii); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(ascii.String(), "\n"), "\n")
+	if len(lines) != tr.Stats().Nodes+1 {
+		t.Errorf("%d ASCII lines for %d nodes:\n%s", len(lines), tr.Stats().Nodes, ascii.String())
+	}
+	if marked := strings.Count(ascii.String(), "<- 100"); marked != tr.Height {
+		t.Errorf("%d nodes marked on the path of a missing key, want %d", marked, tr.Height)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded tree.Structure
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Root == nil || len(decoded.Root.Children) != len(s.Root.Children) || decoded.Size != s.Size {
+		t.Errorf("JSON round trip lost structure: %s", data)
+	}
+
+	empty := tree.NewTree(2, logger.New(logger.Error, io.Discard))
+	var out bytes.Buffer
+	if err := empty.WriteDOT(&out); err != nil || strings.Contains(out.String(), "->") {
+		t.Errorf("empty tree DOT = %q, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,156 @@
+package tree_test
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+)
+
+// byAge indexes values of the form map[string]interface{}{"age": n}.
+func byAge(value interface{}) (int, bool) {
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	switch age := record["age"].(type) {
+	case int:
+		return age, true
+	case float64:
+		return int(age), true
+	}
+	return 0, false
+}
+
+func person(age int) map[string]interface{} {
+	return map[string]interface{}{"age": age}
+}
+
+func TestSecondaryIndexMaintained(t *testing.T) {
+	tr := newTestTree()
+	tr.Insert(1, person(30))
+	tr.Insert(2, person(40))
+	if err := tr.AddIndex("age", byAge); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.AddIndex("age", byAge); err == nil {
+		t.Error("defining an index twice succeeded")
+	}
+
+	tr.Insert(3, person(30))
+	tr.Put(4, "not a person")
+	tr.Put(2, person(30)) // Moves from 40 to 30
+	tr.Delete(1)
+
+	if got, err := tr.LookupBy("age", 30); err != nil || !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("LookupBy(age, 30) = %v, %v; want [2 3]", got, err)
+	}
+	if got, _ := tr.LookupBy("age", 40); len(got) != 0 {
+		t.Errorf("LookupBy(age, 40) = %v, want none", got)
+	}
+	if _, err := tr.LookupBy("name", 1); err == nil {
+		t.Error("LookupBy on a missing index succeeded")
+	}
+
+	// A rolled-back change is undone in the index as well.
+	backup := tr.Clone()
+	tr.Insert(5, person(50))
+	tr.Restore(backup)
+	if got, _ := tr.LookupBy("age", 50); len(got) != 0 {
+		t.Errorf("index kept key %v after rollback", got)
+	}
+
+	tr.Insert(6, person(35))
+	var scanned [][2]int
+	tr.AscendIndex("age", 31, 100, func(secondary, primary int, _ interface{}) bool {
+		scanned = append(scanned, [2]int{secondary, primary})
+		return true
+	})
+	if want := [][2]int{{35, 6}}; !reflect.DeepEqual(scanned, want) {
+		t.Errorf("AscendIndex = %v, want %v", scanned, want)
+	}
+}
+
+func TestSecondaryIndexPersisted(t *testing.T) {
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	tr := newTestTree()
+	tr.AddIndex("age", byAge)
+	for i := 0; i < 100; i++ {
+		tr.Insert(i, person(i%10))
+	}
+	if err := store.SaveTree(tr); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func() *tree.Tree {
+		loaded, err := store.LoadTree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded.Logger = tr.Logger
+		return loaded
+	}
+
+	loaded := load()
+	if _, err := loaded.LookupBy("age", 3); err == nil {
+		t.Error("LookupBy succeeded before the key function was attached")
+	}
+	if err := loaded.AddIndex("age", byAge); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loaded.LookupBy("age", 3)
+	if err != nil || len(got) != 10 || got[0] != 3 || got[9] != 93 {
+		t.Errorf("LookupBy on loaded index = %v, %v", got, err)
+	}
+
+	// Writes made while the index is detached force a rebuild on attach.
+	loaded = load()
+	loaded.Delete(3)
+	loaded.AddIndex("age", byAge)
+	if got, _ := loaded.LookupBy("age", 3); len(got) != 9 || got[0] != 13 {
+		t.Errorf("LookupBy after stale rebuild = %v", got)
+	}
+}
+
+func TestSecondaryIndexLargeKeysPersisted(t *testing.T) {
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	tr := newTestTree()
+	tr.AddIndex("age", byAge)
+	// Neighbouring keys above 2^53 collapse if decoded through float64.
+	big := []int{1<<60 + 1, 1<<60 + 2, math.MaxInt}
+	for _, key := range big {
+		tr.Insert(key, person(7))
+	}
+	if err := store.SaveTree(tr); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.LoadTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.Logger = tr.Logger
+	if err := loaded.AddIndex("age", byAge); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loaded.LookupBy("age", 7); err != nil || !reflect.DeepEqual(got, big) {
+		t.Errorf("LookupBy on loaded index = %v, %v, want %v", got, err, big)
+	}
+
+	// Concurrent readers must not write to the index (run with -race).
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				loaded.LookupBy("age", 7)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,102 @@
+package tree_test
+
+import (
+	"io"
+	"testing"
+
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+)
+
+// insertPanics reports whether inserting key panics.
+func insertPanics(tr *tree.Tree, key int) (panicked bool) {
+	defer func() { panicked = recover() != nil }()
+	tr.Insert(key, key)
+	return false
+}
+
+func TestInvariantModes(t *testing.T) {
+	for _, tc := range []struct {
+		mode  tree.InvariantMode
+		panic bool
+	}{
+		{tree.InvariantsOff, false},
+		{tree.InvariantsLocal, false},
+		{"", false}, // The zero value checks like InvariantsLocal
+		{tree.InvariantsFull, true},
+	} {
+		tr := tree.NewTree(2, logger.New(logger.Error, io.Discard))
+		for i := 0; i < 200; i++ {
+			tr.Insert(i, i)
+		}
+		// Corrupt an internal node far from the path of the next insert.
+		node := tr.Root.Children[0]
+		if node.IsLeaf {
+			t.Fatalf("tree too shallow for the test")
+		}
+		node.Children = node.Children[:len(node.Children)-1]
+
+		tr.Invariants = tc.mode
+		if got := insertPanics(tr, 1000); got != tc.panic {
+			t.Errorf("mode %q: insert panicked = %v, want %v", tc.mode, got, tc.panic)
+		}
+	}
+}
+
+func TestInvariantModeValidation(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want tree.InvariantMode
+		ok   bool
+	}{
+		{"off", tree.InvariantsOff, true},
+		{"local", tree.InvariantsLocal, true},
+		{"full", tree.InvariantsFull, true},
+		{"", tree.InvariantsLocal, true},
+		{"Full", "", false},
+		{"strict", "", false},
+	} {
+		got, err := tree.ParseInvariantMode(tc.in)
+		if got != tc.want || (err == nil) != tc.ok {
+			t.Errorf("ParseInvariantMode(%q) = %q, %v", tc.in, got, err)
+		}
+	}
+
+	tr := newTestTree()
+	settings := tr.Settings()
+	settings.Invariants = "strict"
+	settings.MaxEntries = 5
+	if err := tr.Configure(settings); err == nil {
+		t.Error("Configure accepted an unknown invariant mode")
+	}
+	if got := tr.Settings(); got.Invariants != "" || got.MaxEntries != 0 {
+		t.Errorf("rejected Configure changed settings to %+v", got)
+	}
+	settings.Invariants = ""
+	if err := tr.Configure(settings); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Settings().Invariants; got != tree.InvariantsLocal {
+		t.Errorf("Configure with an empty mode set %q, want local", got)
+	}
+
+	// A mode assigned directly is checked on first use.
+	tr.Insert(1, 1)
+	tr.Invariants = "strict"
+	if !insertPanics(tr, 2) {
+		t.Error("insert with an unknown invariant mode did not panic")
+	}
+}
+
+func BenchmarkInvariantModes(b *testing.B) {
+	for _, mode := range []tree.InvariantMode{tree.InvariantsOff, tree.InvariantsLocal, tree.InvariantsFull} {
+		b.Run(string(mode), func(b *testing.B) {
+			tr := newTestTree()
+			tr.Invariants = mode
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tr.Insert(i, struct{}{})
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package tree_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+)
+
+func TestTextLoggerFields(t *testing.T) {
+	var out bytes.Buffer
+	log := logger.New(logger.Info, &out).With("component", "tree")
+	log.Info("inserted", "key", 42, "value", "two words")
+	log.Debug("hidden", "key", 1)
+	log.Infof("plain %d", 7)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], `logger_test.go:`) ||
+		!strings.HasSuffix(lines[0], `[INFO] inserted component=tree key=42 value="two words"`) {
+		t.Errorf("structured line = %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `[INFO] plain 7 component=tree`) {
+		t.Errorf("printf line = %q", lines[1])
+	}
+}
+
+func TestJSONLoggerFields(t *testing.T) {
+	var out bytes.Buffer
+	base := logger.NewWithHandler(logger.Debug, logger.NewJSONHandler(&out))
+	base.With("request", "abc").Warn("slow", "key", 42, "odd")
+	base.Error("plain")
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	first := records[0]
+	if first["level"] != "WARN" || first["msg"] != "slow" || first["request"] != "abc" ||
+		first["key"] != float64(42) || first["odd"] != "MISSING" {
+		t.Errorf("first record = %v", first)
+	}
+	if caller, _ := first["caller"].(string); !strings.HasPrefix(caller, "logger_test.go:") {
+		t.Errorf("caller = %q", caller)
+	}
+	// With must not leak fields into the parent logger.
+	if _, ok := records[1]["request"]; ok {
+		t.Errorf("parent logger record has field from With: %v", records[1])
+	}
+}
+
+func TestLoggerSetLevel(t *testing.T) {
+	var out bytes.Buffer
+	log := logger.New(logger.Warn, &out)
+	derived := log.With("component", "tree")
+
+	derived.Infof("hidden")
+	log.SetLevel(logger.Info)
+	derived.Infof("shown")
+	if got := out.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "shown") {
+		t.Errorf("output = %q", got)
+	}
+	if derived.Level() != logger.Info {
+		t.Errorf("derived level = %v, want INFO", derived.Level())
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var out bytes.Buffer
+	sh := slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelWarn})
+	log := logger.NewWithHandler(logger.Debug, logger.NewSlogHandler(sh)).With("tree", "main")
+	log.Info("dropped by slog")
+	log.Error("failed", "key", 7)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("slog output %q: %v", out.String(), err)
+	}
+	if record["level"] != "ERROR" || record["msg"] != "failed" || record["tree"] != "main" || record["key"] != float64(7) {
+		t.Errorf("slog record = %v", record)
+	}
+}
+
+func TestTreeOperationLogFields(t *testing.T) {
+	var out bytes.Buffer
+	tr := tree.NewTree(3, logger.NewWithHandler(logger.Debug, logger.NewJSONHandler(&out)))
+	tr.Insert(1, "a")
+	tr.Search(1)
+	tr.Delete(1)
+
+	ops := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		if op, ok := record["op"].(string); ok {
+			ops[op] = record
+		}
+	}
+	for _, op := range []string{tree.OpInsert, tree.OpSearch, tree.OpDelete} {
+		record, ok := ops[op]
+		if !ok {
+			t.Errorf("no %s record in:\n%s", op, out.String())
+			continue
+		}
+		if record["key"] != float64(1) || record["node"] == "" || record["duration"] == "" {
+			t.Errorf("%s record = %v", op, record)
+		}
+	}
+}
+
+func TestSampledHandler(t *testing.T) {
+	var out bytes.Buffer
+	log := logger.NewWithHandler(logger.Info, logger.NewSampledHandler(logger.NewTextHandler(&out), time.Hour, 3, 10))
+	for i := 0; i < 100; i++ {
+		log.Infof("hot %d", i) // One call site
+	}
+	log.Warnf("other call site")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	// The first 3, then every 10th of the remaining 97, then the other site.
+	if len(lines) != 3+9+1 {
+		t.Fatalf("got %d lines, want 13:\n%s", len(lines), out.String())
+	}
+	if !strings.HasSuffix(lines[3], "hot 12") || !strings.HasSuffix(lines[12], "other call site") {
+		t.Errorf("unexpected sampled lines %q, %q", lines[3], lines[12])
+	}
+}
+
+// BenchmarkDisabledLogCall compares a filtered-out log call with slice
+// arguments to the same call behind an Enabled check, as used on hot paths.
+func BenchmarkDisabledLogCall(b *testing.B) {
+	log := logger.New(logger.Info, io.Discard)
+	keys := []int{1, 2, 3, 4, 5}
+	b.Run("Ungated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			log.Debugf("node keys: %v, index %d", keys, i)
+		}
+	})
+	b.Run("Gated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if log.Enabled(logger.Debug) {
+				log.Debugf("node keys: %v, index %d", keys, i)
+			}
+		}
+	})
+}
+
+// BenchmarkInsertLogLevel measures random inserts with tree internals logged
+// (Debug) and filtered out (Info, the default).
+func BenchmarkInsertLogLevel(b *testing.B) {
+	for _, level := range []logger.Level{logger.Debug, logger.Info} {
+		b.Run(level.String(), func(b *testing.B) {
+			tr := tree.NewTree(benchmarkDegree, logger.New(level, io.Discard))
+			rng := rand.New(rand.NewSource(1))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tr.Put(rng.Intn(1<<20), i)
+			}
+		})
+	}
+}
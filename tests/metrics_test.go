@@ -0,0 +1,130 @@
+package tree_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"elastic-btree/internal/server"
+	"elastic-btree/internal/storage"
+	"elastic-btree/pkg/client"
+)
+
+// scrape fetches url and returns its samples keyed by series, e.g.
+// `elastic_btree_operations_total{op="insert"}`.
+func scrape(t *testing.T, url string) map[string]float64 {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	samples := make(map[string]float64)
+	types := make(map[string]bool)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# TYPE ") {
+			types[strings.Fields(line)[2]] = true
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			t.Fatalf("malformed sample line %q", line)
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("malformed sample value in %q: %v", line, err)
+		}
+		series := line[:i]
+		name, _, _ := strings.Cut(series, "{")
+		base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count")
+		if !types[name] && !types[base] {
+			t.Fatalf("sample %q has no TYPE line", series)
+		}
+		samples[series] = value
+	}
+	return samples
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	tr := newTestTree()
+	tr.Insert(-1, "seed")
+	if err := store.SaveTree(tr); err != nil {
+		t.Fatalf("SaveTree: %v", err)
+	}
+	tr, err := store.LoadTree()
+	if err != nil {
+		t.Fatalf("LoadTree: %v", err)
+	}
+	tr.Logger = newTestTree().Logger
+
+	ts := httptest.NewServer(server.New(tr, store, tr.Logger).Handler())
+	defer ts.Close()
+	c := client.New(ts.URL, client.Options{})
+	defer c.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 300; i++ {
+		if err := c.Insert(ctx, i, i); err != nil {
+			t.Fatalf("Insert(%d): %v", i, err)
+		}
+	}
+	for i := 0; i < 50; i++ {
+		if _, _, err := c.Search(ctx, i); err != nil {
+			t.Fatalf("Search(%d): %v", i, err)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		if _, err := c.Delete(ctx, i); err != nil {
+			t.Fatalf("Delete(%d): %v", i, err)
+		}
+	}
+
+	samples := scrape(t, ts.URL+"/metrics")
+	want := map[string]float64{
+		`elastic_btree_operations_total{op="insert"}`:                            300,
+		`elastic_btree_operations_total{op="search"}`:                            50,
+		`elastic_btree_operations_total{op="delete"}`:                            200,
+		`elastic_btree_operation_duration_seconds_count{op="insert"}`:            300,
+		`elastic_btree_operation_duration_seconds_bucket{op="delete",le="+Inf"}`: 200,
+		`elastic_btree_keys`:   101,
+		`elastic_btree_height`: float64(tr.Height),
+		`elastic_btree_storage_duration_seconds_count{op="load"}`: 1,
+		`elastic_btree_storage_duration_seconds_count{op="save"}`: 1,
+	}
+	for series, value := range want {
+		if got, ok := samples[series]; !ok || got != value {
+			t.Errorf("%s = %v (present %v), want %v", series, got, ok, value)
+		}
+	}
+	if samples[`elastic_btree_rebalances_total{event="split"}`] == 0 {
+		t.Errorf("no splits reported")
+	}
+	if samples[`elastic_btree_storage_bytes_total{op="load"}`] == 0 {
+		t.Errorf("no loaded bytes reported")
+	}
+
+	// Buckets are cumulative.
+	prev := 0.0
+	for _, le := range []string{"1e-06", "0.0001", "0.01", "1", "+Inf"} {
+		v := samples[`elastic_btree_operation_duration_seconds_bucket{op="insert",le="`+le+`"}`]
+		if v < prev {
+			t.Fatalf("bucket le=%s is %v, below the previous %v", le, v, prev)
+		}
+		prev = v
+	}
+}
@@ -0,0 +1,85 @@
+package tree_test
+
+import (
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+)
+
+func TestRebuildChangesDegree(t *testing.T) {
+	tr := tree.NewTree(3, logger.New(logger.Error, io.Discard))
+	for i := 0; i < 1000; i++ {
+		tr.Insert(i, i*10)
+	}
+	before := treeContents(tr)
+	version := tr.Version
+
+	if err := tr.Rebuild(8); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if tr.Degree != 8 || tr.Root.MaxKeys != 15 || tr.Root.MinKeys != 7 {
+		t.Fatalf("degree %d, root MaxKeys %d MinKeys %d; want 8, 15, 7", tr.Degree, tr.Root.MaxKeys, tr.Root.MinKeys)
+	}
+	if !tr.ValidateTree() {
+		t.Fatalf("rebuilt tree failed validation")
+	}
+	if got := treeContents(tr); !reflect.DeepEqual(got, before) {
+		t.Fatalf("contents changed by rebuild")
+	}
+	if tr.Version != version {
+		t.Fatalf("version changed from %d to %d", version, tr.Version)
+	}
+
+	// The rebuilt tree keeps working with its new degree.
+	tr.Insert(5000, "new")
+	tr.Delete(10)
+	if !tr.ValidateTree() {
+		t.Fatalf("tree failed validation after writes")
+	}
+
+	if err := tr.Rebuild(1); err == nil {
+		t.Fatalf("Rebuild(1) succeeded, want an error")
+	}
+}
+
+func TestRebuildWithConcurrentWrites(t *testing.T) {
+	tr := newTestTree()
+	for i := 0; i < 5000; i++ {
+		tr.Insert(i, i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 5000; i < 6000; i++ {
+			tr.Insert(i, i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			if _, found := tr.Search(i); !found {
+				t.Errorf("key %d missing during rebuild", i)
+				return
+			}
+		}
+	}()
+	for _, degree := range []int{4, 16, 2} {
+		if err := tr.Rebuild(degree); err != nil {
+			t.Fatalf("Rebuild(%d): %v", degree, err)
+		}
+	}
+	wg.Wait()
+
+	if tr.Size != 6000 || len(treeContents(tr)) != 6000 {
+		t.Fatalf("size %d after concurrent rebuilds, want 6000", tr.Size)
+	}
+	if !tr.ValidateTree() {
+		t.Fatalf("tree failed validation")
+	}
+}
@@ -0,0 +1,168 @@
+package tree_test
+
+import (
+	"bytes"
+	"context"
+	"elastic-btree/internal/server"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/config"
+	"elastic-btree/pkg/logger"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfigReload(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	path := writeConfig(t, `{"logLevel": "info", "maxEntries": 10, "checkpoint": "1m"}`)
+	cfg, _, err := config.Parse([]string{"--config", path, "--checkpoint", "2m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	watcher := config.NewWatcher(cfg, logger.New(logger.Info, &out))
+	var applied []*config.Config
+	watcher.OnChange(func(cfg *config.Config) {
+		applied = append(applied, cfg)
+	})
+
+	// degree needs a restart and checkpoint is pinned by the flag.
+	os.WriteFile(path, []byte(`{"logLevel": "debug", "maxEntries": 5, "checkpoint": "1s", "degree": 9}`), 0644)
+	if err := watcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("OnChange called %d times, want 1", len(applied))
+	}
+	got := watcher.Config()
+	if got != applied[0] || got.LogLevel != logger.Debug || got.MaxEntries != 5 {
+		t.Errorf("reloaded config: level %v, maxEntries %d", got.LogLevel, got.MaxEntries)
+	}
+	if got.TreeDegree != 3 || got.Checkpoint != 2*time.Minute {
+		t.Errorf("degree %d, checkpoint %v changed", got.TreeDegree, got.Checkpoint)
+	}
+	if !strings.Contains(out.String(), "degree cannot change at runtime") {
+		t.Errorf("no warning about degree in %q", out.String())
+	}
+	if cfg.MaxEntries != 10 {
+		t.Error("Reload modified the original config")
+	}
+
+	os.WriteFile(path, []byte(`{"logLevel": "loud", "maxEntries": 1}`), 0644)
+	if err := watcher.Reload(); err == nil {
+		t.Error("invalid config was accepted")
+	}
+	if watcher.Config() != got || len(applied) != 1 {
+		t.Error("invalid config was partly applied")
+	}
+}
+
+// replaceConfig swaps in a new config file with a rename, so a polling
+// watcher never reads it half-written.
+func replaceConfig(t *testing.T, path, data string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigWatcherRun(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	path := writeConfig(t, `{"invariants": "local"}`)
+	cfg, _, err := config.Parse([]string{"--config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := config.NewWatcher(cfg, logger.New(logger.Error, io.Discard))
+	var mu sync.Mutex
+	var mode string
+	watcher.OnChange(func(cfg *config.Config) {
+		mu.Lock()
+		defer mu.Unlock()
+		mode = cfg.Invariants
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx, 10*time.Millisecond)
+
+	replaceConfig(t, path, `{"invariants": "full"}`)
+	waitFor(t, "invariants reload", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return mode == "full"
+	})
+}
+
+func TestTreeConfigure(t *testing.T) {
+	tr := newTestTree()
+	for i := 0; i < 100; i++ {
+		tr.Insert(i, i)
+	}
+
+	settings := tr.Settings()
+	settings.Invariants = tree.InvariantsFull
+	settings.MaxEntries = 10
+	settings.Eviction = tree.EvictSmallest
+	if err := tr.Configure(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	if tr.Settings() != settings {
+		t.Errorf("Settings() = %+v, want %+v", tr.Settings(), settings)
+	}
+	if tr.Size != 10 || tr.Evictions != 90 {
+		t.Errorf("size %d, evictions %d after lowering MaxEntries", tr.Size, tr.Evictions)
+	}
+	if keys := treeKeys(tr); keys[0] != 90 {
+		t.Errorf("smallest key %d, want 90", keys[0])
+	}
+	if !tr.ValidateTree() {
+		t.Error("tree invalid after eviction")
+	}
+}
+
+func TestServerSetCheckpoint(t *testing.T) {
+	tr := newTestTree()
+	path := filepath.Join(t.TempDir(), "tree.json")
+	srv := server.New(tr, storage.NewStorage(path), tr.Logger)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx, listener, 0)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	req, _ := http.NewRequest(http.MethodPut, "http://"+listener.Addr().String()+"/keys/1", strings.NewReader(`{"value": "a"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	srv.SetCheckpoint(10 * time.Millisecond)
+	waitFor(t, "checkpoint save", func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+}
@@ -0,0 +1,197 @@
+package tree_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/replication"
+	"elastic-btree/internal/server"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+)
+
+func treeContents(t *tree.Tree) map[int]interface{} {
+	contents := make(map[int]interface{})
+	t.Ascend(func(key int, value interface{}) bool {
+		contents[key] = value
+		return true
+	})
+	return contents
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReplicationFollowersConverge(t *testing.T) {
+	leaderTree := newTestTree()
+	leader := replication.NewLeader(leaderTree, leaderTree.Logger, 50)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go leader.Serve(ctx, listener)
+
+	// Writes made before any follower exists are only available as a snapshot.
+	for i := 0; i < 200; i++ {
+		leader.Put(i, float64(i))
+	}
+
+	startFollower := func() *replication.Follower {
+		follower := replication.NewFollower(listener.Addr().String(), newTestTree(), leaderTree.Logger)
+		go follower.Run(ctx)
+		return follower
+	}
+	first := startFollower()
+	waitFor(t, "first follower snapshot", func() bool { return first.State().Applied == leader.Seq() })
+
+	// Stream entries, including replacements and deletes.
+	for i := 200; i < 300; i++ {
+		leader.Put(i, float64(i))
+	}
+	leader.Put(5, "replaced")
+	leader.Remove(299)
+
+	// A second follower joins after the retained log no longer reaches seq 1.
+	second := startFollower()
+
+	for _, f := range []*replication.Follower{first, second} {
+		f := f
+		waitFor(t, "follower to catch up", func() bool { return f.Lag() == 0 && f.State().Applied == leader.Seq() })
+		if got, want := treeContents(f.Tree()), treeContents(leaderTree); !reflect.DeepEqual(got, want) {
+			t.Errorf("follower contents differ from leader: %d keys vs %d", len(got), len(want))
+		}
+	}
+
+	waitFor(t, "leader to see acknowledgements", func() bool {
+		followers := leader.Followers()
+		if len(followers) != 2 {
+			return false
+		}
+		for _, f := range followers {
+			if f.Lag != 0 {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestReplicationTTLExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	leaderTree := newTTLTree(clock)
+	leader := replication.NewLeader(leaderTree, leaderTree.Logger, 0)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go leader.Serve(ctx, listener)
+
+	follower := replication.NewFollower(listener.Addr().String(), newTestTree(), leaderTree.Logger)
+	go follower.Run(ctx)
+
+	// TTL writes go to the tree directly; the leader picks them up too.
+	for i := 0; i < 10; i++ {
+		leaderTree.InsertWithTTL(i, float64(i), time.Minute)
+	}
+	leader.Put(100, "kept")
+	caughtUp := func() bool { return follower.State().Applied == leader.Seq() }
+	waitFor(t, "follower to receive TTL keys", caughtUp)
+	if got := len(treeContents(follower.Tree())); got != 11 {
+		t.Fatalf("follower has %d keys, want 11", got)
+	}
+
+	clock.Advance(2 * time.Minute)
+
+	// A lazily expired key is deleted on the follower as well.
+	if _, found := leaderTree.Search(3); found {
+		t.Fatal("expired key 3 found on the leader")
+	}
+	waitFor(t, "lazy expiry to replicate", caughtUp)
+	if _, found := treeContents(follower.Tree())[3]; found {
+		t.Error("lazily expired key 3 is still on the follower")
+	}
+
+	// So are the keys removed by the reaper.
+	if n := leaderTree.ReapExpired(0); n != 9 {
+		t.Fatalf("reaped %d keys, want 9", n)
+	}
+	waitFor(t, "reaped keys to replicate", caughtUp)
+	if got, want := treeContents(follower.Tree()), map[int]interface{}{100: "kept"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("follower contents = %v, want %v", got, want)
+	}
+}
+
+func TestReplicationEvictions(t *testing.T) {
+	leaderTree := newTestTree()
+	leaderTree.MaxEntries = 5
+	leaderTree.Eviction = tree.EvictSmallest
+	leader := replication.NewLeader(leaderTree, leaderTree.Logger, 0)
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	srv := server.New(leaderTree, store, leaderTree.Logger)
+	srv.SetWriter(leader)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go leader.Serve(ctx, listener)
+
+	// The follower is unbounded, so it only loses keys the leader evicts.
+	follower := replication.NewFollower(listener.Addr().String(), newTestTree(), leaderTree.Logger)
+	go follower.Run(ctx)
+	caughtUp := func() bool { return follower.State().Applied == leader.Seq() }
+	waitFor(t, "follower snapshot", caughtUp)
+
+	for i := 1; i <= 8; i++ {
+		leader.Put(i, float64(i))
+	}
+	waitFor(t, "evictions on put to replicate", caughtUp)
+	if got, want := treeKeys(follower.Tree()), []int{4, 5, 6, 7, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("follower keys after puts = %v, want %v", got, want)
+	}
+
+	// Lowering the limit evicts without any write; the change must still be
+	// replicated and saved.
+	if err := srv.Save(); err != nil {
+		t.Fatal(err)
+	}
+	settings := leaderTree.Settings()
+	settings.MaxEntries = 2
+	if err := leaderTree.Configure(settings); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "evictions on configure to replicate", caughtUp)
+	if got, want := treeKeys(follower.Tree()), []int{7, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("follower keys after configure = %v, want %v", got, want)
+	}
+	if err := srv.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := store.LoadTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := treeKeys(saved), []int{7, 8}; !reflect.DeepEqual(got, want) {
+		t.Errorf("saved keys after configure = %v, want %v", got, want)
+	}
+}
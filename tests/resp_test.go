@@ -0,0 +1,281 @@
+package tree_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/server"
+	"elastic-btree/internal/storage"
+)
+
+func TestRESPServer(t *testing.T) {
+	tr := newTestTree()
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	srv := server.New(tr, store, tr.Logger)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.ServeRESP(ctx, listener, 0) }()
+
+	client, err := server.DialRESP(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 1; i <= 500; i++ {
+		if reply, err := client.Do("SET", strconv.Itoa(i), "v"+strconv.Itoa(i)); err != nil || reply != "OK" {
+			t.Fatalf("SET %d: %v %v", i, reply, err)
+		}
+	}
+
+	cases := []struct {
+		args []string
+		want interface{}
+	}{
+		{[]string{"GET", "42"}, "v42"},
+		{[]string{"GET", "1000"}, nil},
+		{[]string{"SET", "42", "x", "NX"}, nil},
+		{[]string{"EXISTS", "1", "2", "1000"}, int64(2)},
+		{[]string{"DEL", "1", "1000"}, int64(1)},
+		{[]string{"DBSIZE"}, int64(499)},
+		{[]string{"ZRANK", "idx", "10"}, int64(8)},
+		{[]string{"ZRANK", "idx", "1"}, nil},
+		{[]string{"ZRANGEBYSCORE", "idx", "(10", "13"}, []interface{}{"11", "12", "13"}},
+		{[]string{"ZRANGEBYSCORE", "idx", "-inf", "+inf", "WITHSCORES", "LIMIT", "1", "2"}, []interface{}{"3", "3", "4", "4"}},
+	}
+	for _, c := range cases {
+		got, err := client.Do(c.args...)
+		if err != nil {
+			t.Fatalf("%v: %v", c.args, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v = %#v, want %#v", c.args, got, c.want)
+		}
+	}
+	if _, err := client.Do("GET", "abc"); err == nil {
+		t.Errorf("GET with non-integer key succeeded")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("ServeRESP: %v", err)
+	}
+	loaded, err := store.LoadTree()
+	if err != nil {
+		t.Fatalf("tree not saved on shutdown: %v", err)
+	}
+	if loaded.Size != 499 {
+		t.Errorf("saved tree size = %d, want 499", loaded.Size)
+	}
+}
+
+// startRESP serves srv over RESP on a loopback port until the test ends.
+func startRESP(t *testing.T, srv *server.Server) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.ServeRESP(ctx, listener, 0) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return listener.Addr().String()
+}
+
+func TestRESPSetConditional(t *testing.T) {
+	tr := newTestTree()
+	srv := server.New(tr, storage.NewStorage(filepath.Join(t.TempDir(), "tree.json")), tr.Logger)
+	addr := startRESP(t, srv)
+
+	// Concurrent SET NX on one key must let exactly one client win.
+	const clients = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := server.DialRESP(addr)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer client.Close()
+			reply, err := client.Do("SET", "7", "v"+strconv.Itoa(i), "NX")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if reply == "OK" {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if winners != 1 {
+		t.Fatalf("%d clients won SET NX, want 1", winners)
+	}
+
+	client, err := server.DialRESP(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	cases := []struct {
+		args []string
+		want interface{}
+	}{
+		{[]string{"SET", "8", "x", "XX"}, nil},
+		{[]string{"GET", "8"}, nil},
+		{[]string{"SET", "7", "x", "XX"}, "OK"},
+		{[]string{"GET", "7"}, "x"},
+		{[]string{"SET", "8", "y", "NX"}, "OK"},
+		{[]string{"GET", "8"}, "y"},
+	}
+	for _, c := range cases {
+		got, err := client.Do(c.args...)
+		if err != nil {
+			t.Fatalf("%v: %v", c.args, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v = %#v, want %#v", c.args, got, c.want)
+		}
+	}
+}
+
+func TestRESPSetExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tr := newTTLTree(clock)
+	srv := server.New(tr, storage.NewStorage(filepath.Join(t.TempDir(), "tree.json")), tr.Logger)
+	client, err := server.DialRESP(startRESP(t, srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, args := range [][]string{{"SET", "1", "a", "EX", "10"}, {"SET", "2", "b", "px", "1500"}, {"SET", "3", "c"}} {
+		if reply, err := client.Do(args...); err != nil || reply != "OK" {
+			t.Fatalf("%v = %v, %v", args, reply, err)
+		}
+	}
+	for key, want := range map[int]time.Duration{1: 10 * time.Second, 2: 1500 * time.Millisecond} {
+		if left, ok := tr.TTL(key); !ok || left != want {
+			t.Errorf("TTL(%d) = %v, %v, want %v", key, left, ok, want)
+		}
+	}
+	if _, ok := tr.TTL(3); ok {
+		t.Error("plain SET stored a TTL")
+	}
+
+	clock.Advance(2 * time.Second)
+	if reply, _ := client.Do("GET", "2"); reply != nil {
+		t.Errorf("GET of an expired key = %#v, want nil", reply)
+	}
+	if reply, _ := client.Do("GET", "1"); reply != "a" {
+		t.Errorf("GET 1 = %#v, want \"a\"", reply)
+	}
+
+	for _, args := range [][]string{
+		{"SET", "4", "d", "EX"},
+		{"SET", "4", "d", "EX", "0"},
+		{"SET", "4", "d", "EX", "ten"},
+		{"SET", "4", "d", "EX", "1", "PX", "1000"},
+		{"SET", "4", "d", "NX", "EX", "1"},
+		{"SET", "4", "d", "NX", "XX"},
+		{"SET", "4", "d", "KEEPTTL"},
+	} {
+		if reply, err := client.Do(args...); err == nil {
+			t.Errorf("%v = %#v, want an error", args, reply)
+		}
+	}
+	if _, found := tr.Search(4); found {
+		t.Error("a rejected SET stored key 4")
+	}
+}
+
+func TestRESPArrayLengthLimit(t *testing.T) {
+	tr := newTestTree()
+	srv := server.New(tr, storage.NewStorage(filepath.Join(t.TempDir(), "tree.json")), tr.Logger)
+	addr := startRESP(t, srv)
+
+	// The server must reject the command rather than wait for a billion
+	// arguments.
+	expectProtocolError(t, addr, "*1073741824\r\n", "-ERR Protocol error: invalid array length")
+}
+
+func TestRESPBulkLengthLimit(t *testing.T) {
+	tr := newTestTree()
+	srv := server.New(tr, storage.NewStorage(filepath.Join(t.TempDir(), "tree.json")), tr.Logger)
+	addr := startRESP(t, srv)
+
+	for _, header := range []string{"$1073741824", "$-1", "$abc"} {
+		expectProtocolError(t, addr, "*2\r\n$3\r\nGET\r\n"+header+"\r\n", "-ERR Protocol error: invalid bulk length")
+	}
+	expectProtocolError(t, addr, "*1\r\n:1\r\n", "-ERR Protocol error: expected bulk string")
+
+	// A large declared length within the limit is read as the data arrives,
+	// not allocated up front.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := io.WriteString(conn, "*3\r\n$3\r\nSET\r\n$1\r\n1\r\n$100000000\r\npartial"); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if n, err := conn.Read(make([]byte, 1)); n != 0 || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("read during partial bulk string = %d, %v, want a timeout", n, err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 10<<20 {
+		t.Errorf("server allocated %d bytes for a 7-byte partial bulk string", allocated)
+	}
+}
+
+// expectProtocolError sends input on a new connection and checks that the
+// server replies with an error starting with want and then hangs up.
+func expectProtocolError(t *testing.T, addr, input, want string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, input); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("%q: read failed: %v", input, err)
+	}
+	if !strings.HasPrefix(string(reply), want) || !strings.HasSuffix(string(reply), "\r\n") {
+		t.Errorf("%q: reply = %q, want %q...", input, reply, want)
+	}
+}
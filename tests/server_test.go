@@ -0,0 +1,193 @@
+package tree_test
+
+import (
+	"bytes"
+	"elastic-btree/internal/server"
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newHTTPServer serves a fresh tree over HTTP for the duration of the test.
+func newHTTPServer(t *testing.T) (*httptest.Server, *tree.Tree) {
+	tr := newTestTree()
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	ts := httptest.NewServer(server.New(tr, store, tr.Logger).Handler())
+	t.Cleanup(ts.Close)
+	return ts, tr
+}
+
+// doRequest sends a request with an optional JSON body and decodes the JSON
+// response into out, if given. It returns the status code.
+func doRequest(t *testing.T, method, url string, body, out interface{}) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestHTTPKeys(t *testing.T) {
+	ts, _ := newHTTPServer(t)
+
+	if status := doRequest(t, "PUT", ts.URL+"/keys/42", map[string]interface{}{"value": "a"}, nil); status != http.StatusCreated {
+		t.Errorf("first PUT status = %d, want 201", status)
+	}
+	if status := doRequest(t, "PUT", ts.URL+"/keys/42", map[string]interface{}{"value": "b"}, nil); status != http.StatusOK {
+		t.Errorf("second PUT status = %d, want 200", status)
+	}
+
+	var pair server.Pair
+	if status := doRequest(t, "GET", ts.URL+"/keys/42", nil, &pair); status != http.StatusOK || pair.Value != "b" {
+		t.Errorf("GET = %d %+v", status, pair)
+	}
+	if status := doRequest(t, "GET", ts.URL+"/keys/x", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("GET invalid key status = %d, want 400", status)
+	}
+	if status := doRequest(t, "DELETE", ts.URL+"/keys/42", nil, nil); status != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want 204", status)
+	}
+	if status := doRequest(t, "DELETE", ts.URL+"/keys/42", nil, nil); status != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d, want 404", status)
+	}
+	if status := doRequest(t, "GET", ts.URL+"/keys/42", nil, nil); status != http.StatusNotFound {
+		t.Errorf("GET deleted key status = %d, want 404", status)
+	}
+}
+
+func TestHTTPKeysTTL(t *testing.T) {
+	ts, tr := newHTTPServer(t)
+
+	if status := doRequest(t, "PUT", ts.URL+"/keys/1?ttl=1m", map[string]interface{}{"value": "a"}, nil); status != http.StatusCreated {
+		t.Errorf("PUT with ttl status = %d, want 201", status)
+	}
+	if left, ok := tr.TTL(1); !ok || left <= 0 || left > time.Minute {
+		t.Errorf("TTL(1) = %v, %v, want up to a minute", left, ok)
+	}
+	if status := doRequest(t, "PUT", ts.URL+"/keys/1?ttl=2h", map[string]interface{}{"value": "b"}, nil); status != http.StatusOK {
+		t.Errorf("PUT replacing with ttl status = %d, want 200", status)
+	}
+	if left, ok := tr.TTL(1); !ok || left <= time.Hour {
+		t.Errorf("TTL(1) = %v, %v after replacing, want over an hour", left, ok)
+	}
+	// A plain PUT clears the TTL.
+	doRequest(t, "PUT", ts.URL+"/keys/1", map[string]interface{}{"value": "c"}, nil)
+	if _, ok := tr.TTL(1); ok {
+		t.Error("plain PUT kept the TTL")
+	}
+
+	for _, ttl := range []string{"0s", "-1m", "soon"} {
+		if status := doRequest(t, "PUT", ts.URL+"/keys/2?ttl="+ttl, map[string]interface{}{"value": "d"}, nil); status != http.StatusBadRequest {
+			t.Errorf("PUT with ttl=%s status = %d, want 400", ttl, status)
+		}
+	}
+	if _, found := tr.Search(2); found {
+		t.Error("a rejected PUT stored key 2")
+	}
+}
+
+func TestHTTPRange(t *testing.T) {
+	ts, tr := newHTTPServer(t)
+	keys := []int{-1000000, -1000, -5, 0, 5, 1000, 1000000}
+	for _, key := range keys {
+		tr.Insert(key, strconv.Itoa(key))
+	}
+
+	rangeKeys := func(query string) []int {
+		t.Helper()
+		var pairs []server.Pair
+		if status := doRequest(t, "GET", ts.URL+"/range"+query, nil, &pairs); status != http.StatusOK {
+			t.Fatalf("GET /range%s status = %d", query, status)
+		}
+		got := []int{}
+		for _, p := range pairs {
+			got = append(got, p.Key)
+		}
+		return got
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", keys},
+		{"?from=-5", []int{-5, 0, 5, 1000, 1000000}},
+		{"?to=5", []int{-1000000, -1000, -5, 0, 5}},
+		{"?from=-1000&to=1000", []int{-1000, -5, 0, 5, 1000}},
+		{"?from=1&to=4", []int{}},
+		{"?limit=2", []int{-1000000, -1000}},
+	}
+	for _, tt := range tests {
+		if got := rangeKeys(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET /range%s = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"?from=x", "?to=1.5", "?limit=0", "?limit=100000"} {
+		if status := doRequest(t, "GET", ts.URL+"/range"+query, nil, nil); status != http.StatusBadRequest {
+			t.Errorf("GET /range%s status = %d, want 400", query, status)
+		}
+	}
+}
+
+func TestHTTPBatchAndStats(t *testing.T) {
+	ts, _ := newHTTPServer(t)
+
+	ops := []server.BatchOp{
+		{Op: "put", Key: 1, Value: "a"},
+		{Op: "put", Key: 2, Value: "b"},
+		{Op: "get", Key: 1},
+		{Op: "delete", Key: 2},
+		{Op: "get", Key: 2},
+	}
+	var results []server.BatchResult
+	if status := doRequest(t, "POST", ts.URL+"/batch", ops, &results); status != http.StatusOK {
+		t.Fatalf("POST /batch status = %d", status)
+	}
+	found := []bool{false, false, true, true, false}
+	for i, result := range results {
+		if result.Found != found[i] {
+			t.Errorf("result %d = %+v, want found %v", i, result, found[i])
+		}
+	}
+	if results[2].Value != "a" {
+		t.Errorf("get 1 = %v, want a", results[2].Value)
+	}
+
+	bad := []server.BatchOp{{Op: "put", Key: 3, Value: "c"}, {Op: "frobnicate", Key: 3}}
+	if status := doRequest(t, "POST", ts.URL+"/batch", bad, nil); status != http.StatusBadRequest {
+		t.Errorf("malformed batch status = %d, want 400", status)
+	}
+
+	var stats server.Stats
+	if status := doRequest(t, "GET", ts.URL+"/stats", nil, &stats); status != http.StatusOK || stats.Size != 1 {
+		t.Errorf("GET /stats = %d %+v, want size 1 (malformed batch not applied)", status, stats)
+	}
+}
@@ -0,0 +1,185 @@
+package tree_test
+
+import (
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+)
+
+// leafCapacities returns the MaxKeys of every leaf in the tree.
+func leafCapacities(tr *tree.Tree) []int {
+	var caps []int
+	var walk func(n *tree.Node)
+	walk = func(n *tree.Node) {
+		if n.IsLeaf {
+			caps = append(caps, n.MaxKeys)
+			return
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	if tr.Root != nil {
+		walk(tr.Root)
+	}
+	return caps
+}
+
+func TestAdaptiveSizingGrowsAppendLeaves(t *testing.T) {
+	tr := tree.NewTree(4, logger.New(logger.Error, io.Discard))
+	tr.Sizing = tree.AdaptiveSizing{MaxKeys: 64}
+	for i := 0; i < 2000; i++ {
+		tr.Insert(i, i)
+	}
+	if !tr.ValidateTree() {
+		t.Fatalf("tree failed validation")
+	}
+
+	caps := leafCapacities(tr)
+	if largest := caps[len(caps)-2]; largest != 64 {
+		t.Fatalf("leaf capacity after sequential appends = %d, want 64", largest)
+	}
+
+	fixed := tree.NewTree(4, logger.New(logger.Error, io.Discard))
+	for i := 0; i < 2000; i++ {
+		fixed.Insert(i, i)
+	}
+	if len(caps) >= len(leafCapacities(fixed)) {
+		t.Fatalf("adaptive tree has %d leaves, fixed tree %d", len(caps), len(leafCapacities(fixed)))
+	}
+}
+
+func TestAdaptiveSizingShrinksUpdatedLeaves(t *testing.T) {
+	tr := tree.NewTree(8, logger.New(logger.Error, io.Discard))
+	tr.Sizing = tree.AdaptiveSizing{}
+	for _, i := range rand.New(rand.NewSource(1)).Perm(500) {
+		tr.Put(i*2, i)
+	}
+	// Hammer a hot region with updates, then insert into it.
+	for round := 0; round < 20; round++ {
+		for i := 400; i < 500; i += 2 {
+			tr.Put(i, round)
+		}
+	}
+	for i := 401; i < 500; i += 2 {
+		tr.Put(i, i)
+	}
+	if !tr.ValidateTree() {
+		t.Fatalf("tree failed validation")
+	}
+
+	smallest := 2*tr.Degree - 1
+	for _, c := range leafCapacities(tr) {
+		smallest = min(smallest, c)
+	}
+	if smallest >= 2*tr.Degree-1 {
+		t.Fatalf("no leaf shrank below the default capacity %d", 2*tr.Degree-1)
+	}
+}
+
+func TestAdaptiveSizingMatchesModel(t *testing.T) {
+	for _, degree := range []int{2, 3, 5} {
+		rng := rand.New(rand.NewSource(int64(degree)))
+		tr := tree.NewTree(degree, logger.New(logger.Error, io.Discard))
+		tr.Sizing = tree.AdaptiveSizing{}
+		model := make(map[int]interface{})
+
+		next := 0
+		for step := 0; step < 5000; step++ {
+			switch r := rng.Intn(10); {
+			case r < 4:
+				// Sequential appends grow leaves.
+				tr.Put(next, step)
+				model[next] = step
+				next++
+			case r < 7:
+				key := rng.Intn(next + 1)
+				tr.Put(key, step)
+				model[key] = step
+			default:
+				key := rng.Intn(next + 1)
+				tr.Delete(key)
+				delete(model, key)
+			}
+
+			if tr.Size != len(model) {
+				t.Fatalf("degree %d step %d: size %d, want %d", degree, step, tr.Size, len(model))
+			}
+			if !tr.ValidateTree() {
+				t.Fatalf("degree %d step %d: tree failed validation", degree, step)
+			}
+		}
+		if got := treeContents(tr); !reflect.DeepEqual(got, model) {
+			t.Fatalf("degree %d: contents differ from model (%d keys vs %d)", degree, len(got), len(model))
+		}
+	}
+}
+
+func TestAdaptiveSizingMixedPutDelete(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		for _, degree := range []int{2, 3} {
+			rng := rand.New(rand.NewSource(seed))
+			tr := tree.NewTree(degree, logger.New(logger.Error, io.Discard))
+			tr.Sizing = tree.AdaptiveSizing{MaxKeys: 64}
+			model := make(map[int]interface{})
+
+			for step := 0; step < 5000; step++ {
+				switch r := rng.Intn(4); {
+				case r == 0:
+					// Appends grow leaves beyond the degree.
+					tr.Put(step, step)
+					model[step] = step
+				case r < 3:
+					key := rng.Intn(500)
+					tr.Put(key, step)
+					model[key] = step
+				default:
+					key := rng.Intn(500)
+					tr.Delete(key)
+					delete(model, key)
+				}
+
+				if tr.Size != len(model) {
+					t.Fatalf("seed %d degree %d step %d: size %d, want %d", seed, degree, step, tr.Size, len(model))
+				}
+				if !tr.ValidateTree() {
+					t.Fatalf("seed %d degree %d step %d: tree failed validation", seed, degree, step)
+				}
+			}
+			if got := treeContents(tr); !reflect.DeepEqual(got, model) {
+				t.Fatalf("seed %d degree %d: contents differ from model", seed, degree)
+			}
+		}
+	}
+}
+
+func benchmarkSizing(b *testing.B, workload func(b *testing.B, tr *tree.Tree)) {
+	policies := []struct {
+		name   string
+		policy tree.SizingPolicy
+	}{
+		{"Fixed", tree.FixedSizing{}},
+		{"Adaptive", tree.AdaptiveSizing{}},
+	}
+	for _, p := range policies {
+		b.Run(p.name, func(b *testing.B) {
+			tr := tree.NewTree(16, logger.New(logger.Error, io.Discard))
+			tr.Sizing = p.policy
+			workload(b, tr)
+			b.ReportMetric(float64(len(leafCapacities(tr))), "leaves")
+		})
+	}
+}
+
+func BenchmarkSizingSequentialAppend(b *testing.B) {
+	benchmarkSizing(b, func(b *testing.B, tr *tree.Tree) {
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tr.Insert(i, struct{}{})
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package tree_test
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+)
+
+func TestStats(t *testing.T) {
+	tr := tree.NewTree(3, logger.New(logger.Error, io.Discard))
+	if stats := tr.Stats(); stats.Nodes != 0 || stats.LevelNodes != nil {
+		t.Fatalf("empty tree stats = %+v", stats)
+	}
+
+	for i := 0; i < 500; i++ {
+		tr.Insert(i, i)
+	}
+	stats := tr.Stats()
+	if stats.Size != 500 || stats.Height != tr.Height || len(stats.LevelNodes) != tr.Height {
+		t.Fatalf("size %d height %d levels %d; want 500, %d, %d", stats.Size, stats.Height, len(stats.LevelNodes), tr.Height, tr.Height)
+	}
+	if stats.LevelNodes[0] != 1 || stats.LevelNodes[len(stats.LevelNodes)-1] != stats.Leaves {
+		t.Fatalf("level counts %v do not match %d leaves", stats.LevelNodes, stats.Leaves)
+	}
+	total, histogram := 0, 0
+	for _, n := range stats.LevelNodes {
+		total += n
+	}
+	for _, n := range stats.FillHistogram {
+		histogram += n
+	}
+	if total != stats.Nodes || histogram != stats.Nodes || stats.Leaves+stats.Internal != stats.Nodes {
+		t.Fatalf("nodes %d, per level %d, in histogram %d, leaves+internal %d",
+			stats.Nodes, total, histogram, stats.Leaves+stats.Internal)
+	}
+	if stats.FillFactor <= 0 || stats.FillFactor > 1 || stats.Bytes <= 0 {
+		t.Fatalf("fill factor %v, bytes %d", stats.FillFactor, stats.Bytes)
+	}
+	// Each split adds a node, and each root split a new root as well.
+	if stats.Splits != uint64(stats.Nodes-stats.Height) || stats.Merges != 0 || stats.Borrows != 0 {
+		t.Fatalf("splits %d merges %d borrows %d after inserts into %d nodes",
+			stats.Splits, stats.Merges, stats.Borrows, stats.Nodes)
+	}
+
+	for _, i := range rand.New(rand.NewSource(1)).Perm(400) {
+		tr.Delete(i)
+	}
+	stats = tr.Stats()
+	if stats.Merges == 0 || stats.Borrows == 0 {
+		t.Fatalf("merges %d borrows %d after deletes", stats.Merges, stats.Borrows)
+	}
+}
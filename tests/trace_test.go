@@ -0,0 +1,137 @@
+package tree_test
+
+import (
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/logger"
+)
+
+// recordingTracer counts the callbacks it receives.
+type recordingTracer struct {
+	mu                              sync.Mutex
+	started, ended                  map[string]int
+	visits, splits, merges, borrows int
+	saves                           int
+	savedBytes                      int
+	saveErr                         error
+}
+
+func newRecordingTracer() *recordingTracer {
+	return &recordingTracer{started: make(map[string]int), ended: make(map[string]int)}
+}
+
+func (r *recordingTracer) OpStart(op string, key int) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started[op]++
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.ended[op]++
+	}
+}
+
+func (r *recordingTracer) Visit(node *tree.Node) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.visits++
+}
+
+func (r *recordingTracer) Split(node, sibling *tree.Node) { r.splits++ }
+func (r *recordingTracer) Merge(node, sibling *tree.Node) { r.merges++ }
+func (r *recordingTracer) Borrow(from, to *tree.Node)     { r.borrows++ }
+
+func (r *recordingTracer) Save(path string, bytes int, elapsed time.Duration, err error) {
+	r.saves++
+	r.savedBytes = bytes
+	r.saveErr = err
+}
+
+func TestTracerCallbacks(t *testing.T) {
+	tr := tree.NewTree(2, logger.New(logger.Error, io.Discard))
+	rec := newRecordingTracer()
+	tr.Tracer = rec
+
+	for i := 0; i < 200; i++ {
+		tr.Insert(i, i)
+	}
+	tr.Put(5, "updated")
+	for i := 0; i < 50; i++ {
+		tr.Search(i)
+	}
+	for i := 0; i < 150; i++ {
+		tr.Delete(i)
+	}
+
+	want := map[string]int{tree.OpInsert: 201, tree.OpSearch: 50, tree.OpDelete: 150}
+	for op, n := range want {
+		if rec.started[op] != n || rec.ended[op] != n {
+			t.Errorf("%s: %d starts, %d ends; want %d", op, rec.started[op], rec.ended[op], n)
+		}
+	}
+	stats := tr.Stats()
+	if uint64(rec.splits) != stats.Splits || uint64(rec.merges) != stats.Merges || uint64(rec.borrows) != stats.Borrows {
+		t.Errorf("traced %d splits, %d merges, %d borrows; stats report %d, %d, %d",
+			rec.splits, rec.merges, rec.borrows, stats.Splits, stats.Merges, stats.Borrows)
+	}
+	if rec.splits == 0 || rec.merges == 0 || rec.visits < 401 {
+		t.Errorf("traced %d splits, %d merges, %d visits", rec.splits, rec.merges, rec.visits)
+	}
+
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	if err := store.SaveTree(tr); err != nil {
+		t.Fatalf("SaveTree: %v", err)
+	}
+	if rec.saves != 1 || rec.savedBytes == 0 || rec.saveErr != nil {
+		t.Errorf("traced %d saves of %d bytes (err %v)", rec.saves, rec.savedBytes, rec.saveErr)
+	}
+}
+
+func benchmarkTracer(b *testing.B, op func(b *testing.B, tr *tree.Tree)) {
+	tracers := []struct {
+		name   string
+		tracer tree.Tracer
+	}{
+		{"None", nil},
+		{"Nop", tree.NopTracer{}},
+	}
+	for _, tc := range tracers {
+		b.Run(tc.name, func(b *testing.B) {
+			tr := newTestTree()
+			tr.Tracer = tc.tracer
+			op(b, tr)
+		})
+	}
+}
+
+func BenchmarkTracerSearch(b *testing.B) {
+	benchmarkTracer(b, func(b *testing.B, tr *tree.Tree) {
+		for i := 0; i < numPreloadKeys; i++ {
+			tr.Insert(i, struct{}{})
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tr.Search(i % numPreloadKeys)
+		}
+	})
+}
+
+func BenchmarkTracerPut(b *testing.B) {
+	benchmarkTracer(b, func(b *testing.B, tr *tree.Tree) {
+		for i := 0; i < numPreloadKeys; i++ {
+			tr.Insert(i, struct{}{})
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tr.Put(i%numPreloadKeys, i)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package tree_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/storage"
+	"elastic-btree/internal/tree"
+)
+
+// fakeClock is a manually advanced time source for TTL tests.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTTLTree(clock *fakeClock) *tree.Tree {
+	tr := newTestTree()
+	tr.Clock = clock.Now
+	return tr
+}
+
+func TestTTLLazyExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tr := newTTLTree(clock)
+
+	tr.InsertWithTTL(1, "short", time.Second)
+	tr.InsertWithTTL(2, "long", time.Minute)
+	tr.Insert(3, "forever")
+
+	if left, ok := tr.TTL(1); !ok || left != time.Second {
+		t.Errorf("TTL(1) = %v, %v", left, ok)
+	}
+	clock.Advance(2 * time.Second)
+
+	if _, found := tr.Search(1); found {
+		t.Error("expired key 1 was found")
+	}
+	if tr.Size != 2 {
+		t.Errorf("size after lazy expiry = %d, want 2", tr.Size)
+	}
+	for _, key := range []int{2, 3} {
+		if _, found := tr.Search(key); !found {
+			t.Errorf("key %d missing", key)
+		}
+	}
+
+	// A plain write clears the TTL.
+	tr.Put(2, "kept")
+	clock.Advance(time.Hour)
+	if value, found := tr.Search(2); !found || value != "kept" {
+		t.Errorf("Search(2) = %v, %v after Put cleared its TTL", value, found)
+	}
+}
+
+func TestTTLReaper(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tr := newTTLTree(clock)
+
+	for i := 0; i < 500; i++ {
+		ttl := time.Second
+		if i%2 == 0 {
+			ttl = time.Hour
+		}
+		tr.InsertWithTTL(i, i, ttl)
+	}
+	clock.Advance(time.Minute)
+
+	if n := tr.ReapExpired(100); n != 100 {
+		t.Errorf("first batch reaped %d keys, want 100", n)
+	}
+	if n := tr.ReapExpired(0); n != 150 {
+		t.Errorf("second pass reaped %d keys, want 150", n)
+	}
+	if tr.Size != 250 || !tr.ValidateTree() {
+		t.Fatalf("size %d after reaping, want 250 and a valid tree", tr.Size)
+	}
+	tr.Ascend(func(key int, _ interface{}) bool {
+		if key%2 != 0 {
+			t.Errorf("expired key %d survived", key)
+		}
+		return true
+	})
+
+	clock.Advance(2 * time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tr.RunReaper(ctx, time.Millisecond, 10)
+	waitFor(t, "reaper to empty the tree", func() bool {
+		tr.Lock.RLock()
+		defer tr.Lock.RUnlock()
+		return tr.Size == 0
+	})
+}
+
+func TestTTLPersisted(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tr := newTTLTree(clock)
+	tr.InsertWithTTL(1, "a", time.Minute)
+	tr.Insert(2, "b")
+
+	store := storage.NewStorage(filepath.Join(t.TempDir(), "tree.json"))
+	if err := store.SaveTree(tr); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.LoadTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.Logger = tr.Logger
+	loaded.Clock = clock.Now
+
+	if left, ok := loaded.TTL(1); !ok || left != time.Minute {
+		t.Errorf("loaded TTL(1) = %v, %v", left, ok)
+	}
+	if _, ok := loaded.TTL(2); ok {
+		t.Error("key 2 gained a TTL")
+	}
+	clock.Advance(time.Hour)
+	if _, found := loaded.Search(1); found {
+		t.Error("loaded key 1 did not expire")
+	}
+}
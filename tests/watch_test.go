@@ -0,0 +1,99 @@
+package tree_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"elastic-btree/internal/tree"
+	"elastic-btree/pkg/client"
+)
+
+func TestTreeWatch(t *testing.T) {
+	tr := newTestTree()
+	watcher := tr.Watch(10, 20, 0)
+	defer watcher.Close()
+
+	tr.Insert(5, "outside")
+	tr.Insert(10, "a")
+	tr.Put(10, "b")
+	tr.Insert(25, "outside")
+	tr.Delete(10)
+	tr.Delete(15) // Missing keys produce no event
+
+	want := []tree.ChangeEvent{
+		{Op: tree.OpInsert, Key: 10, NewValue: "a", Version: 2},
+		{Op: tree.OpUpdate, Key: 10, OldValue: "a", NewValue: "b", Version: 3},
+		{Op: tree.OpDelete, Key: 10, OldValue: "b", Version: 5},
+	}
+	for _, w := range want {
+		if got := <-watcher.C; got != w {
+			t.Errorf("got event %+v, want %+v", got, w)
+		}
+	}
+	select {
+	case got := <-watcher.C:
+		t.Errorf("unexpected event %+v", got)
+	default:
+	}
+}
+
+func TestTreeWatchOverflow(t *testing.T) {
+	tr := newTestTree()
+	watcher := tr.WatchAll(2)
+
+	for i := 0; i < 3; i++ {
+		tr.Insert(i, i)
+	}
+	count := 0
+	for range watcher.C {
+		count++
+	}
+	if count != 2 || watcher.Err() != tree.ErrWatchOverflow {
+		t.Errorf("got %d events and err %v, want 2 and overflow", count, watcher.Err())
+	}
+	watcher.Close() // Closing a dropped watcher is harmless
+}
+
+func TestClientWatch(t *testing.T) {
+	ts := newTestServer(t, nil)
+	c := client.New(ts.URL, client.Options{})
+	defer c.Close()
+	ctx := context.Background()
+
+	events := make(chan client.Event, 1000)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Watch(ctx, 100, math.MaxInt, func(event client.Event) bool {
+			events <- event
+			return event.Key != 200
+		})
+	}()
+
+	// Keep writing until the stream is established and delivers an event.
+	for i := 0; len(events) == 0; i++ {
+		if i == 500 {
+			t.Fatal("no events received")
+		}
+		c.Insert(ctx, 1, "outside")
+		c.Insert(ctx, 100, i)
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Insert(ctx, 200, "last")
+
+	if err := <-done; err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	close(events)
+	var last client.Event
+	for event := range events {
+		if event.Key != 100 && event.Key != 200 {
+			t.Errorf("got event for key %d outside the watched range", event.Key)
+		}
+		last = event
+	}
+	if last.Op != tree.OpInsert || last.Key != 200 || last.NewValue != "last" {
+		t.Errorf("last event = %+v", last)
+	}
+}